@@ -0,0 +1,212 @@
+// Package client is a Go client for the widad HTTP API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+// Job and Status are re-exported so that programs outside this module can
+// build and inspect jobs.
+type (
+	Job     = core.Job
+	Status  = core.Status
+	JobLog  = core.JobLog
+	JobTree = core.JobTree
+)
+
+const (
+	StatusPending   = core.StatusPending
+	StatusRunning   = core.StatusRunning
+	StatusSuccess   = core.StatusSuccess
+	StatusFailed    = core.StatusFailed
+	StatusDead      = core.StatusDead
+	StatusCancelled = core.StatusCancelled
+	StatusDiscarded = core.StatusDiscarded
+
+	// NoRetries as a job's MaxRetries stops its queue's default applying.
+	NoRetries = core.NoRetries
+)
+
+// ErrWaitTimeout is returned by EnqueueAndWait, together with the job's
+// latest state, when the job did not finish within the wait.
+var ErrWaitTimeout = errors.New("wida: job did not finish before the wait timeout")
+
+// APIError is a non-2xx response from the server.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("wida: %s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// New returns a client for the server at baseURL, e.g. "http://localhost:8080".
+// It has no overall request timeout; bound calls with their context instead.
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    baseURL,
+		HTTPClient: &http.Client{},
+	}
+}
+
+// Enqueue submits a job and returns it as stored by the server.
+func (c *Client) Enqueue(ctx context.Context, job *Job) (*Job, error) {
+	var out Job
+	if _, err := c.do(ctx, http.MethodPost, "/api/jobs/enqueue", job, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// EnqueueAndWait submits a job and blocks until it reaches a terminal status
+// or wait elapses, in which case the job is returned with ErrWaitTimeout.
+// The server caps wait at a few minutes.
+func (c *Client) EnqueueAndWait(ctx context.Context, job *Job, wait time.Duration) (*Job, error) {
+	var out Job
+	path := "/api/jobs/enqueue?wait=" + url.QueryEscape(wait.String())
+	status, err := c.do(ctx, http.MethodPost, path, job, &out)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusAccepted {
+		return &out, ErrWaitTimeout
+	}
+	return &out, nil
+}
+
+// GetJob fetches a job, including its result. It returns nil if the job
+// does not exist.
+func (c *Client) GetJob(ctx context.Context, id string) (*Job, error) {
+	var out Job
+	_, err := c.do(ctx, http.MethodGet, "/api/jobs/"+url.PathEscape(id), nil, &out)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Cancel cancels a pending job, or asks the worker running it to stop. In
+// the latter case the returned job has CancelRequested set.
+func (c *Client) Cancel(ctx context.Context, id string) (*Job, error) {
+	var out Job
+	if _, err := c.do(ctx, http.MethodPost, "/api/jobs/"+url.PathEscape(id)+"/cancel", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// JobTree fetches the job with the jobs it spawned, and theirs, nested
+// under it. truncated reports that the server left some out because the
+// tree is very large.
+func (c *Client) JobTree(ctx context.Context, id string) (tree *JobTree, truncated bool, err error) {
+	var out struct {
+		Tree      *JobTree `json:"tree"`
+		Truncated bool     `json:"truncated"`
+	}
+	if _, err := c.do(ctx, http.MethodGet, "/api/jobs/"+url.PathEscape(id)+"/tree", nil, &out); err != nil {
+		return nil, false, err
+	}
+	return out.Tree, out.Truncated, nil
+}
+
+// JobLogs returns the job's log lines after the line with ID afterID,
+// oldest first. The server returns at most a page of lines per call.
+func (c *Client) JobLogs(ctx context.Context, id string, afterID int64) ([]*JobLog, error) {
+	var out struct {
+		Logs []*JobLog `json:"logs"`
+	}
+	path := fmt.Sprintf("/api/jobs/%s/logs?after=%d", url.PathEscape(id), afterID)
+	if _, err := c.do(ctx, http.MethodGet, path, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Logs, nil
+}
+
+// FollowJobLogs calls fn with each of the job's log lines as they are
+// written, returning once the job finishes or ctx ends.
+func (c *Client) FollowJobLogs(ctx context.Context, id string, fn func(*JobLog)) error {
+	path := "/api/jobs/" + url.PathEscape(id) + "/logs?follow=true"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Message: string(bytes.TrimSpace(msg))}
+	}
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var l JobLog
+		if err := dec.Decode(&l); err == io.EOF {
+			return ctx.Err()
+		} else if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("wida: decoding log line: %w", err)
+		}
+		fn(&l)
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) (int, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return 0, err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return 0, err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, &APIError{StatusCode: resp.StatusCode, Message: string(bytes.TrimSpace(msg))}
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("wida: decoding response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// JobOption customises a job built by Enqueue.
+type JobOption func(*Job)
+
+func WithID(id string) JobOption            { return func(j *Job) { j.ID = id } }
+func WithQueue(queue string) JobOption      { return func(j *Job) { j.Queue = queue } }
+func WithTimeout(d time.Duration) JobOption { return func(j *Job) { j.Timeout = d } }
+func WithRunAt(t time.Time) JobOption       { return func(j *Job) { j.RunAt = &t } }
+func WithExecutor(name string) JobOption    { return func(j *Job) { j.Executor = name } }
+func WithPriority(p int) JobOption          { return func(j *Job) { j.Priority = p } }
+func WithConcurrencyKey(key string, limit int) JobOption {
+	return func(j *Job) { j.ConcurrencyKey, j.ConcurrencyLimit = key, limit }
+}
+
+// WithMaxRetries caps how many times the job is retried. 0 means no retries,
+// overriding the queue's default.
+func WithMaxRetries(n int) JobOption {
+	return func(j *Job) {
+		if n == 0 {
+			n = NoRetries
+		}
+		j.MaxRetries = n
+	}
+}
+
+// WithWeight makes the job occupy weight of a worker's slots while it runs.
+func WithWeight(weight int) JobOption { return func(j *Job) { j.Weight = weight } }
+
+// WithRequiredLabels only lets workers with all of labels run the job.
+func WithRequiredLabels(labels map[string]string) JobOption {
+	return func(j *Job) { j.RequiredLabels = labels }
+}
+
+// WithPreferredLabels lets workers with all of labels claim the job first.
+func WithPreferredLabels(labels map[string]string) JobOption {
+	return func(j *Job) { j.PreferredLabels = labels }
+}
+
+// Enqueue submits a job of jobType with args encoded as its payload, for a
+// worker that registered a matching handler.Handle. If args has a
+// Validate() error method it must pass before anything is sent. Jobs go to
+// the "default" queue under a generated ID unless options say otherwise.
+func Enqueue[T any](ctx context.Context, c *Client, jobType string, args T, opts ...JobOption) (*Job, error) {
+	if v, ok := any(&args).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("wida: invalid %s args: %w", jobType, err)
+		}
+	}
+
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("wida: encoding %s args: %w", jobType, err)
+	}
+
+	job := &Job{
+		ID:      fmt.Sprintf("job-%d", time.Now().UnixNano()),
+		Queue:   "default",
+		Type:    jobType,
+		Payload: payload,
+		Status:  StatusPending,
+	}
+	for _, opt := range opts {
+		opt(job)
+	}
+	return c.Enqueue(ctx, job)
+}
@@ -1,55 +1,199 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"net/http"
 	"os"
+	"sort"
 	"time"
 
-	"github.com/theb0imanuu/wida/internal/core"
+	"github.com/theb0imanuu/wida/client"
 )
 
+const apiURL = "http://localhost:8080"
+
+const usage = `Usage:
+  widactl enqueue [-wait <duration>] <queue> <payload>
+  widactl get <job-id>
+  widactl cancel <job-id>
+  widactl logs [-f] <job-id>
+  widactl tree <job-id>`
+
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: widactl enqueue <queue> <payload>")
+		fmt.Println(usage)
 		os.Exit(1)
 	}
 
 	command := os.Args[1]
+	c := client.New(apiURL)
+	ctx := context.Background()
 
 	switch command {
 	case "enqueue":
-		if len(os.Args) < 4 {
-			fmt.Println("Usage: widactl enqueue <queue> <payload>")
+		fs := flag.NewFlagSet("enqueue", flag.ExitOnError)
+		wait := fs.Duration("wait", 0, "block until the job finishes, up to this long")
+		fs.Parse(os.Args[2:])
+		if fs.NArg() < 2 {
+			fmt.Println("Usage: widactl enqueue [-wait <duration>] <queue> <payload>")
 			os.Exit(1)
 		}
-		queue := os.Args[2]
-		payload := os.Args[3]
+		queue := fs.Arg(0)
+		payload := fs.Arg(1)
 
-		job := core.Job{
+		job := &client.Job{
 			ID:      fmt.Sprintf("job-%d", time.Now().UnixNano()),
 			Queue:   queue,
 			Payload: json.RawMessage(payload),
-			Status:  core.StatusPending,
+			Status:  client.StatusPending,
 		}
 
-		jobBytes, _ := json.Marshal(job)
-		resp, err := http.Post("http://localhost:8080/api/jobs/enqueue", "application/json", bytes.NewBuffer(jobBytes))
+		if *wait <= 0 {
+			if _, err := c.Enqueue(ctx, job); err != nil {
+				fmt.Printf("Failed to enqueue job: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("Job enqueued successfully:", job.ID)
+			return
+		}
+
+		done, err := c.EnqueueAndWait(ctx, job, *wait)
+		if errors.Is(err, client.ErrWaitTimeout) {
+			fmt.Printf("Job %s still %s after %v\n", job.ID, done.Status, *wait)
+			os.Exit(2)
+		}
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			fmt.Printf("Failed to enqueue job: %v\n", err)
+			os.Exit(1)
+		}
+		printJSON(done)
+		if done.Status != client.StatusSuccess {
 			os.Exit(1)
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode == http.StatusCreated {
-			fmt.Println("Job enqueued successfully:", job.ID)
+	case "get":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: widactl get <job-id>")
+			os.Exit(1)
+		}
+		jobID := os.Args[2]
+
+		job, err := c.GetJob(ctx, jobID)
+		if err != nil {
+			fmt.Printf("Failed to get job: %v\n", err)
+			os.Exit(1)
+		}
+		if job == nil {
+			fmt.Println("Job not found:", jobID)
+			os.Exit(1)
+		}
+		printJSON(job)
+
+	case "cancel":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: widactl cancel <job-id>")
+			os.Exit(1)
+		}
+		jobID := os.Args[2]
+
+		job, err := c.Cancel(ctx, jobID)
+		if err != nil {
+			fmt.Printf("Failed to cancel job: %v\n", err)
+			os.Exit(1)
+		}
+		if job.CancelRequested {
+			fmt.Println("Cancellation requested, the worker will stop the job shortly:", jobID)
 		} else {
-			fmt.Printf("Failed to enqueue job, status code: %d\n", resp.StatusCode)
+			fmt.Println("Job cancelled:", jobID)
+		}
+
+	case "logs":
+		fs := flag.NewFlagSet("logs", flag.ExitOnError)
+		follow := fs.Bool("f", false, "keep printing new lines until the job finishes")
+		fs.Parse(os.Args[2:])
+		if fs.NArg() < 1 {
+			fmt.Println("Usage: widactl logs [-f] <job-id>")
+			os.Exit(1)
+		}
+		jobID := fs.Arg(0)
+
+		if *follow {
+			if err := c.FollowJobLogs(ctx, jobID, printLog); err != nil {
+				fmt.Printf("Failed to follow logs: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		var after int64
+		for {
+			logs, err := c.JobLogs(ctx, jobID, after)
+			if err != nil {
+				fmt.Printf("Failed to get logs: %v\n", err)
+				os.Exit(1)
+			}
+			if len(logs) == 0 {
+				return
+			}
+			for _, l := range logs {
+				printLog(l)
+				after = l.ID
+			}
+		}
+
+	case "tree":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: widactl tree <job-id>")
+			os.Exit(1)
+		}
+		jobID := os.Args[2]
+
+		tree, truncated, err := c.JobTree(ctx, jobID)
+		if err != nil {
+			fmt.Printf("Failed to get job tree: %v\n", err)
+			os.Exit(1)
+		}
+		printTree(tree, "")
+		if truncated {
+			fmt.Println("(tree truncated)")
 		}
 
 	default:
 		fmt.Println("Unknown command")
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+}
+
+// printLog prints a job log line as "time LEVEL #attempt message key=value...".
+func printLog(l *client.JobLog) {
+	line := fmt.Sprintf("%s %-5s #%d %s", l.Time.Local().Format("2006-01-02 15:04:05.000"), l.Level, l.Attempt, l.Message)
+	keys := make([]string, 0, len(l.Fields))
+	for k := range l.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		line += fmt.Sprintf(" %s=%v", k, l.Fields[k])
 	}
+	fmt.Println(line)
+}
+
+// printTree prints each job as "id [status] type", indented under its parent.
+func printTree(t *client.JobTree, indent string) {
+	line := fmt.Sprintf("%s%s [%s]", indent, t.ID, t.Status)
+	if t.Type != "" {
+		line += " " + t.Type
+	}
+	fmt.Println(line)
+	for _, c := range t.Children {
+		printTree(c, indent+"  ")
+	}
+}
+
+func printJSON(v interface{}) {
+	out, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Println(string(out))
 }
@@ -2,17 +2,23 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"github.com/theb0imanuu/wida/internal/api"
+	"github.com/theb0imanuu/wida/internal/config"
 	"github.com/theb0imanuu/wida/internal/core"
+	"github.com/theb0imanuu/wida/internal/executor"
 	"github.com/theb0imanuu/wida/internal/scheduler"
 	"github.com/theb0imanuu/wida/internal/store/postgres"
 	"github.com/theb0imanuu/wida/internal/worker"
@@ -20,9 +26,9 @@ import (
 
 type MockExecutor struct{}
 
-func (m *MockExecutor) Execute(ctx context.Context, job *core.Job) error {
+func (m *MockExecutor) Execute(ctx context.Context, job *core.Job) (json.RawMessage, error) {
 	log.Printf("MockExecutor: Executing job %s...\n", job.ID)
-	return nil
+	return nil, nil
 }
 
 func main() {
@@ -64,15 +70,47 @@ func main() {
 	store := postgres.NewStore(pool)
 	apiServer := api.NewServer(store)
 
+	// Queue definitions from the config file are written over those stored,
+	// so the file stays the source of truth for the queues it names.
+	// WIDA_QUEUES lists the queues this node works, with optional weights
+	// for WIDA_QUEUE_MODE=weighted, e.g. "high:6,default:3,low:1".
+	queues := []string{"default", "high", "low"}
+	var queueWeights map[string]int
+	if q := os.Getenv("WIDA_QUEUES"); q != "" {
+		if queues, queueWeights, err = worker.ParseQueues(q); err != nil {
+			log.Fatalf("Invalid WIDA_QUEUES: %v\n", err)
+		}
+	}
+	if path := os.Getenv("WIDA_CONFIG"); path != "" {
+		cfg, err := config.Load(path)
+		if err != nil {
+			log.Fatalf("Invalid WIDA_CONFIG: %v\n", err)
+		}
+		for _, q := range cfg.Queues {
+			if err := store.SaveQueue(ctx, q.QueueConfig()); err != nil {
+				log.Fatalf("Failed to save queue %s: %v\n", q.Name, err)
+			}
+			if !slices.Contains(queues, q.Name) {
+				queues = append(queues, q.Name)
+			}
+		}
+		log.Printf("Loaded %d queue definitions from %s\n", len(cfg.Queues), path)
+	}
+
 	port := os.Getenv("WIDA_PORT")
 	if port == "" {
 		port = "8080"
 	}
 
+	// Requests run under httpCtx, which shutdown cancels so that job streams,
+	// followed logs and waiting enqueues end instead of holding it up.
+	httpCtx, cancelHTTP := context.WithCancel(ctx)
 	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: apiServer.ServeMux(),
+		Addr:        ":" + port,
+		Handler:     apiServer.ServeMux(),
+		BaseContext: func(net.Listener) context.Context { return httpCtx },
 	}
+	srv.RegisterOnShutdown(cancelHTTP)
 	go func() {
 		log.Printf("Listening on :%s\n", port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -84,6 +122,14 @@ func main() {
 	sched.Start(ctx)
 	apiServer.SetScheduler(sched)
 
+	numWorkers := 1
+	if c := os.Getenv("WIDA_WORKERS"); c != "" {
+		if val, err := strconv.Atoi(c); err == nil {
+			numWorkers = val
+		}
+	}
+
+	// Jobs each worker runs at once.
 	concurrency := 5
 	if c := os.Getenv("WIDA_WORKER_CONCURRENCY"); c != "" {
 		if val, err := strconv.Atoi(c); err == nil {
@@ -91,10 +137,95 @@ func main() {
 		}
 	}
 
-	queues := []string{"default", "high", "low"}
+	// How long shutdown waits for running jobs before handing them back.
+	drainTimeout := 30 * time.Second
+	if d := os.Getenv("WIDA_DRAIN_TIMEOUT"); d != "" {
+		if val, err := time.ParseDuration(d); err == nil {
+			drainTimeout = val
+		}
+	}
+
 	workerPool := worker.NewPool("widad-node-1", store, queues)
+	workerPool.QueueWeights = queueWeights
+	if l := os.Getenv("WIDA_WORKER_LABELS"); l != "" {
+		if workerPool.Labels, err = core.ParseLabels(l); err != nil {
+			log.Fatalf("Invalid WIDA_WORKER_LABELS: %v\n", err)
+		}
+	}
+	switch mode := worker.QueueMode(os.Getenv("WIDA_QUEUE_MODE")); mode {
+	case "":
+	case worker.QueueModeFIFO, worker.QueueModeStrict, worker.QueueModeWeighted:
+		workerPool.QueueMode = mode
+	default:
+		log.Fatalf("Invalid WIDA_QUEUE_MODE %q: want fifo, strict or weighted\n", mode)
+	}
+	// WIDA_MAX_WORKERS turns on autoscaling between WIDA_MIN_WORKERS and it,
+	// starting from WIDA_WORKERS.
+	if c := os.Getenv("WIDA_MAX_WORKERS"); c != "" {
+		maxWorkers, err := strconv.Atoi(c)
+		if err != nil {
+			log.Fatalf("Invalid WIDA_MAX_WORKERS: %v\n", err)
+		}
+		scale := &worker.AutoscaleConfig{Min: 1, Max: maxWorkers}
+		if c := os.Getenv("WIDA_MIN_WORKERS"); c != "" {
+			if scale.Min, err = strconv.Atoi(c); err != nil {
+				log.Fatalf("Invalid WIDA_MIN_WORKERS: %v\n", err)
+			}
+		}
+		if d := os.Getenv("WIDA_SCALE_TARGET_WAIT"); d != "" {
+			if scale.TargetWait, err = time.ParseDuration(d); err != nil {
+				log.Fatalf("Invalid WIDA_SCALE_TARGET_WAIT: %v\n", err)
+			}
+		}
+		if d := os.Getenv("WIDA_SCALE_DOWN_DELAY"); d != "" {
+			if scale.ScaleDownDelay, err = time.ParseDuration(d); err != nil {
+				log.Fatalf("Invalid WIDA_SCALE_DOWN_DELAY: %v\n", err)
+			}
+		}
+		workerPool.Autoscale = scale
+	}
+	// WIDA_BREAKERS=true turns on circuit breakers, tuned by the
+	// WIDA_BREAKER_* variables.
+	if on, _ := strconv.ParseBool(os.Getenv("WIDA_BREAKERS")); on {
+		cfg := worker.DefaultBreakerConfig
+		for name, n := range map[string]*int{
+			"WIDA_BREAKER_WINDOW":          &cfg.Window,
+			"WIDA_BREAKER_MIN_EXECUTIONS":  &cfg.MinExecutions,
+			"WIDA_BREAKER_PROBES":          &cfg.Probes,
+			"WIDA_BREAKER_EXECUTOR_QUEUES": &cfg.ExecutorQueues,
+		} {
+			if c := os.Getenv(name); c != "" {
+				if *n, err = strconv.Atoi(c); err != nil {
+					log.Fatalf("Invalid %s: %v\n", name, err)
+				}
+			}
+		}
+		if c := os.Getenv("WIDA_BREAKER_FAILURE_RATE"); c != "" {
+			if cfg.FailureRate, err = strconv.ParseFloat(c, 64); err != nil {
+				log.Fatalf("Invalid WIDA_BREAKER_FAILURE_RATE: %v\n", err)
+			}
+		}
+		if d := os.Getenv("WIDA_BREAKER_COOLDOWN"); d != "" {
+			if cfg.Cooldown, err = time.ParseDuration(d); err != nil {
+				log.Fatalf("Invalid WIDA_BREAKER_COOLDOWN: %v\n", err)
+			}
+		}
+		workerPool.Breakers = worker.NewBreakers(cfg)
+	}
 	workerPool.RegisterExecutor("default", &MockExecutor{})
-	workerPool.Start(ctx, concurrency)
+	workerPool.RegisterExecutor("http", executor.NewHTTPExecutor([]byte(os.Getenv("WIDA_WEBHOOK_SECRET"))))
+
+	// Subprocess jobs may only run commands allow-listed here, as a JSON
+	// object of name -> executor.Command.
+	if c := os.Getenv("WIDA_SUBPROCESS_COMMANDS"); c != "" {
+		var commands map[string]executor.Command
+		if err := json.Unmarshal([]byte(c), &commands); err != nil {
+			log.Fatalf("Invalid WIDA_SUBPROCESS_COMMANDS: %v\n", err)
+		}
+		workerPool.RegisterExecutor("subprocess", executor.NewSubprocessExecutor(commands))
+	}
+	workerPool.Start(ctx, numWorkers, concurrency)
+	apiServer.SetPool(workerPool)
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -102,8 +233,20 @@ func main() {
 	<-sigChan
 	log.Println("Shutting down widad...")
 
-	srv.Shutdown(ctx)
-	workerPool.Stop()
+	// The workers drain while the API shuts down, both within the drain
+	// timeout.
+	drained := make(chan struct{})
+	go func() {
+		workerPool.Stop(drainTimeout)
+		close(drained)
+	}()
+	shutdownCtx, cancelShutdown := context.WithTimeout(ctx, drainTimeout)
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("API server shutdown: %v\n", err)
+		srv.Close()
+	}
+	cancelShutdown()
+	<-drained
 	sched.Stop()
 	cancel()
 }
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+// Handlers can write to the job's own log, served by GET
+// /api/jobs/{id}/logs, and long-running ones can report how far they have
+// got and save checkpoints to resume from if an attempt fails:
+//
+//	handler.Handle(reg, "backfill", func(ctx context.Context, args Backfill) error {
+//		start := 0
+//		if cp := handler.Checkpoint(ctx); cp != nil {
+//			start, _ = strconv.Atoi(string(cp))
+//		}
+//		for day := start; day < args.Days; day++ {
+//			handler.Logger(ctx).Info("backfilling", "day", day)
+//			if err := backfillDay(ctx, day); err != nil {
+//				return err
+//			}
+//			handler.SaveCheckpoint(ctx, []byte(strconv.Itoa(day+1)))
+//			handler.ReportProgress(ctx, 100*float64(day+1)/float64(args.Days), "")
+//		}
+//		return nil
+//	})
+//
+// Outside a job they do nothing, and Logger returns slog.Default().
+//
+// A handler that discovers more work can spawn child jobs, enqueued only if
+// it succeeds, and a continuation that runs once all of them have:
+//
+//	handler.Handle(reg, "crawl", func(ctx context.Context, args Crawl) error {
+//		for _, url := range findPages(ctx, args.Site) {
+//			if err := handler.Spawn(ctx, "fetch_page", FetchPage{URL: url}); err != nil {
+//				return err
+//			}
+//		}
+//		return handler.ContinueWith(ctx, "build_index", BuildIndex{Site: args.Site})
+//	})
+
+// ErrNoJob is returned by Spawn and ContinueWith outside a job, where there
+// is no job for the children to belong to.
+var ErrNoJob = errors.New("handler: not running in a job")
+
+// ReportProgress records the running job's progress, between 0 and 100.
+func ReportProgress(ctx context.Context, percent float64, message string) error {
+	if jc := core.JobContextFromContext(ctx); jc != nil {
+		return jc.ReportProgress(percent, message)
+	}
+	return nil
+}
+
+// SaveCheckpoint stores data for the job's next attempt to resume from.
+func SaveCheckpoint(ctx context.Context, data []byte) error {
+	if jc := core.JobContextFromContext(ctx); jc != nil {
+		return jc.SaveCheckpoint(data)
+	}
+	return nil
+}
+
+// Logger returns a logger writing to the job's log.
+func Logger(ctx context.Context) *slog.Logger {
+	if jc := core.JobContextFromContext(ctx); jc != nil {
+		return jc.Logger()
+	}
+	return slog.Default()
+}
+
+// Checkpoint returns the job's last saved checkpoint, or nil.
+func Checkpoint(ctx context.Context) []byte {
+	if jc := core.JobContextFromContext(ctx); jc != nil {
+		return jc.Checkpoint()
+	}
+	return nil
+}
+
+// Spawn adds a child job of jobType with args as its payload, to be enqueued
+// when the running job succeeds.
+func Spawn[T any](ctx context.Context, jobType string, args T) error {
+	job, err := typedJob(jobType, args)
+	if err != nil {
+		return err
+	}
+	return SpawnJobs(ctx, job)
+}
+
+// SpawnJobs adds fully built child jobs, to be enqueued when the running job
+// succeeds.
+func SpawnJobs(ctx context.Context, children ...*core.Job) error {
+	jc := core.JobContextFromContext(ctx)
+	if jc == nil {
+		return ErrNoJob
+	}
+	return jc.Spawn(children...)
+}
+
+// ContinueWith sets a job of jobType to run once every spawned child has
+// succeeded, or as soon as the running job does if it spawned none.
+func ContinueWith[T any](ctx context.Context, jobType string, args T) error {
+	jc := core.JobContextFromContext(ctx)
+	if jc == nil {
+		return ErrNoJob
+	}
+	job, err := typedJob(jobType, args)
+	if err != nil {
+		return err
+	}
+	return jc.ContinueWith(job)
+}
+
+// typedJob builds a job for the handler registered for jobType.
+func typedJob[T any](jobType string, args T) (*core.Job, error) {
+	if v, ok := any(&args).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid %s args: %w", jobType, err)
+		}
+	}
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s args: %w", jobType, err)
+	}
+	return &core.Job{Type: jobType, Payload: payload}, nil
+}
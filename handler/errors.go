@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+// Handlers classify failures by wrapping the errors they return. A plain
+// error is retried according to the job's retry policy.
+
+// Permanent fails the job without retrying; it moves straight to the DLQ.
+func Permanent(err error) error { return core.Permanent(err) }
+
+// RetryAfter retries the job after d instead of the policy's backoff.
+func RetryAfter(d time.Duration, err error) error { return core.RetryAfter(d, err) }
+
+// Snooze runs the job again after d without counting an attempt.
+func Snooze(d time.Duration) error { return core.Snooze(d) }
+
+// Discard drops the job without retrying it or adding it to the DLQ.
+func Discard(err error) error { return core.Discard(err) }
@@ -0,0 +1,35 @@
+package handler_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/theb0imanuu/wida/handler"
+	"github.com/theb0imanuu/wida/worker"
+)
+
+type Welcome struct {
+	UserID string `json:"user_id"`
+}
+
+func sendWelcome(ctx context.Context, userID string) error { return nil }
+
+// Typed handlers are run by a pool from the public worker package.
+func Example() {
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, "postgres://localhost:5432/wida")
+	if err != nil {
+		return
+	}
+
+	reg := handler.NewRegistry()
+	handler.Handle(reg, "send_welcome", func(ctx context.Context, args Welcome) error {
+		return sendWelcome(ctx, args.UserID)
+	})
+
+	pool := worker.NewPool("mailer-1", db, []string{"emails"})
+	pool.RegisterExecutor(worker.DefaultExecutor, reg)
+	pool.Start(ctx, 1, 5)
+	defer pool.Stop(30 * time.Second)
+}
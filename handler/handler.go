@@ -0,0 +1,100 @@
+// Package handler lets jobs be written as typed Go functions instead of
+// executors that unmarshal job.Payload by hand.
+//
+//	type Welcome struct{ UserID string `json:"user_id"` }
+//
+//	reg := handler.NewRegistry()
+//	handler.Handle(reg, "send_welcome", func(ctx context.Context, args Welcome) error {
+//		return mailer.SendWelcome(ctx, args.UserID)
+//	})
+//
+//	pool := worker.NewPool("mailer-1", db, []string{"emails"})
+//	pool.RegisterExecutor(worker.DefaultExecutor, reg)
+//	pool.Start(ctx, 1, 5)
+//
+// The pool comes from the public worker package. Producers enqueue with
+// client.Enqueue, which encodes the same struct.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+type handlerFunc func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error)
+
+// Registry is a core.Executor that routes each job to the handler registered
+// for its Type. Jobs of an unknown type, and payloads that do not decode or
+// validate, fail permanently: retrying them cannot help.
+type Registry struct {
+	mu       sync.RWMutex
+	handlers map[string]handlerFunc
+}
+
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[string]handlerFunc)}
+}
+
+// Handle registers fn for jobs of jobType. The payload is decoded into a T
+// and, if T has a Validate() error method, validated before fn runs.
+func Handle[T any](r *Registry, jobType string, fn func(ctx context.Context, args T) error) {
+	HandleWithResult(r, jobType, func(ctx context.Context, args T) (struct{}, error) {
+		return struct{}{}, fn(ctx, args)
+	})
+}
+
+// HandleWithResult is like Handle for handlers that produce a result, which
+// is stored on the job as JSON.
+func HandleWithResult[T, R any](r *Registry, jobType string, fn func(ctx context.Context, args T) (R, error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers[jobType] = func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+		var args T
+		if err := json.Unmarshal(payload, &args); err != nil {
+			return nil, core.Permanent(fmt.Errorf("decoding %s payload: %w", jobType, err))
+		}
+		if v, ok := any(&args).(interface{ Validate() error }); ok {
+			if err := v.Validate(); err != nil {
+				return nil, core.Permanent(fmt.Errorf("invalid %s payload: %w", jobType, err))
+			}
+		}
+
+		result, err := fn(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		if _, empty := any(result).(struct{}); empty {
+			return nil, nil
+		}
+		return json.Marshal(result)
+	}
+}
+
+func (r *Registry) Execute(ctx context.Context, job *core.Job) (json.RawMessage, error) {
+	r.mu.RLock()
+	h, ok := r.handlers[job.Type]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, core.Permanent(fmt.Errorf("no handler registered for job type %q", job.Type))
+	}
+	return h(ctx, job.Payload)
+}
+
+// Types lists the registered job types.
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]string, 0, len(r.handlers))
+	for t := range r.handlers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
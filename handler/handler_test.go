@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+type resize struct {
+	URL   string `json:"url"`
+	Width int    `json:"width"`
+}
+
+func (r resize) Validate() error {
+	if r.Width <= 0 {
+		return errors.New("width must be positive")
+	}
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry()
+
+	var got resize
+	Handle(reg, "resize", func(ctx context.Context, args resize) error {
+		got = args
+		return nil
+	})
+	HandleWithResult(reg, "double", func(ctx context.Context, n int) (int, error) {
+		return n * 2, nil
+	})
+	Handle(reg, "flaky", func(ctx context.Context, args struct{}) error {
+		return errors.New("try again")
+	})
+
+	run := func(jobType, payload string) (json.RawMessage, error) {
+		return reg.Execute(context.Background(), &core.Job{Type: jobType, Payload: json.RawMessage(payload)})
+	}
+
+	if _, err := run("resize", `{"url":"a.png","width":64}`); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.URL != "a.png" || got.Width != 64 {
+		t.Errorf("Expected decoded args, got %+v", got)
+	}
+
+	result, err := run("double", `21`)
+	if err != nil || string(result) != "42" {
+		t.Errorf("Expected result 42, got %s (%v)", result, err)
+	}
+
+	if _, err := run("resize", `{"url":"a.png","width":0}`); !core.IsPermanent(err) {
+		t.Errorf("Expected invalid args to fail permanently, got %v", err)
+	}
+	if _, err := run("resize", `"not an object"`); !core.IsPermanent(err) {
+		t.Errorf("Expected undecodable payload to fail permanently, got %v", err)
+	}
+	if _, err := run("unknown", `{}`); !core.IsPermanent(err) {
+		t.Errorf("Expected unknown type to fail permanently, got %v", err)
+	}
+	if _, err := run("flaky", `{}`); err == nil || core.IsPermanent(err) {
+		t.Errorf("Expected handler errors to stay retryable, got %v", err)
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	cause := errors.New("boom")
+
+	if err := Permanent(cause); !core.IsPermanent(err) || !errors.Is(err, cause) {
+		t.Errorf("Permanent(%v) = %v", cause, err)
+	}
+	if d, ok := core.RetryAfterDelay(RetryAfter(time.Minute, cause)); !ok || d != time.Minute {
+		t.Errorf("RetryAfter delay = %v, %v", d, ok)
+	}
+	if d, ok := core.SnoozeDelay(fmt.Errorf("wrapped: %w", Snooze(30*time.Second))); !ok || d != 30*time.Second {
+		t.Errorf("Snooze delay = %v, %v", d, ok)
+	}
+	if err := Discard(cause); !core.IsDiscard(err) || core.IsPermanent(err) || !errors.Is(err, cause) {
+		t.Errorf("Discard(%v) = %v", cause, err)
+	}
+}
+
+type fakeJobContext struct {
+	progress   []float64
+	checkpoint []byte
+	children   []*core.Job
+	next       *core.Job
+}
+
+func (f *fakeJobContext) ReportProgress(percent float64, message string) error {
+	f.progress = append(f.progress, percent)
+	return nil
+}
+
+func (f *fakeJobContext) SaveCheckpoint(data []byte) error {
+	f.checkpoint = data
+	return nil
+}
+
+func (f *fakeJobContext) Checkpoint() []byte { return f.checkpoint }
+
+func (f *fakeJobContext) Logger() *slog.Logger { return slog.Default() }
+
+func (f *fakeJobContext) Spawn(children ...*core.Job) error {
+	f.children = append(f.children, children...)
+	return nil
+}
+
+func (f *fakeJobContext) ContinueWith(next *core.Job) error {
+	f.next = next
+	return nil
+}
+
+func TestJobContextHelpers(t *testing.T) {
+	ctx := context.Background()
+	if err := ReportProgress(ctx, 50, "halfway"); err != nil || Checkpoint(ctx) != nil {
+		t.Fatalf("helpers outside a job: err %v, checkpoint %q", err, Checkpoint(ctx))
+	}
+
+	jc := &fakeJobContext{checkpoint: []byte("day-3")}
+	ctx = core.WithJobContext(ctx, jc)
+
+	reg := NewRegistry()
+	Handle(reg, "backfill", func(ctx context.Context, args struct{}) error {
+		if got := string(Checkpoint(ctx)); got != "day-3" {
+			t.Errorf("resumed from checkpoint %q, want day-3", got)
+		}
+		SaveCheckpoint(ctx, []byte("day-4"))
+		return ReportProgress(ctx, 40, "day 4 of 10")
+	})
+	if _, err := reg.Execute(ctx, &core.Job{Type: "backfill", Payload: json.RawMessage(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if string(jc.checkpoint) != "day-4" || len(jc.progress) != 1 || jc.progress[0] != 40 {
+		t.Errorf("job context got checkpoint %q, progress %v", jc.checkpoint, jc.progress)
+	}
+}
+
+func TestSpawn(t *testing.T) {
+	if err := Spawn(context.Background(), "resize", resize{Width: 10}); err != ErrNoJob {
+		t.Fatalf("Spawn outside a job = %v, want ErrNoJob", err)
+	}
+
+	jc := &fakeJobContext{}
+	ctx := core.WithJobContext(context.Background(), jc)
+	if err := Spawn(ctx, "resize", resize{Width: -1}); err == nil {
+		t.Error("invalid child args accepted")
+	}
+	if err := Spawn(ctx, "resize", resize{Width: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ContinueWith(ctx, "index", struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(jc.children) != 1 || jc.children[0].Type != "resize" || string(jc.children[0].Payload) != `{"url":"","width":10}` {
+		t.Errorf("children = %+v", jc.children)
+	}
+	if jc.next == nil || jc.next.Type != "index" {
+		t.Errorf("continuation = %+v", jc.next)
+	}
+}
@@ -1,17 +1,44 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/theb0imanuu/wida/internal/core"
 	"github.com/theb0imanuu/wida/internal/scheduler"
 	"github.com/theb0imanuu/wida/internal/store"
+	"github.com/theb0imanuu/wida/internal/worker"
 )
 
+// MaxEnqueueWait caps the ?wait= duration accepted by HandleEnqueue.
+const MaxEnqueueWait = 5 * time.Minute
+
+// jobLogPage caps how many log lines one read of a job's log returns.
+const jobLogPage = 1000
+
+// jobTreeLimit caps how many jobs one job tree returns.
+const jobTreeLimit = 1000
+
+// logFollowInterval is how often a followed job log is checked for new lines.
+const logFollowInterval = time.Second
+
+// streamKeepAlive is how often an idle job stream sends a comment, so
+// proxies do not close it.
+const streamKeepAlive = 15 * time.Second
+
+// workerLiveness is how recent a worker's heartbeat must be for it to count
+// as running.
+const workerLiveness = time.Minute
+
 type Server struct {
 	store     store.Store
 	scheduler *scheduler.Scheduler
+	pool      *worker.Pool
 }
 
 func NewServer(s store.Store) *Server {
@@ -22,12 +49,24 @@ func (s *Server) SetScheduler(sched *scheduler.Scheduler) {
 	s.scheduler = sched
 }
 
+// SetPool lets the workers API report the local pool's scaling decisions.
+func (s *Server) SetPool(p *worker.Pool) {
+	s.pool = p
+}
+
 func (s *Server) ServeMux() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/jobs/enqueue", s.HandleEnqueue)
 	mux.HandleFunc("/api/jobs/", s.HandleGetJob) // Handles /api/jobs and /api/jobs/{id}
+	mux.HandleFunc("/api/jobs/{id}/cancel", s.HandleCancelJob)
+	mux.HandleFunc("/api/jobs/{id}/stream", s.HandleStreamJob)
+	mux.HandleFunc("/api/jobs/{id}/logs", s.HandleJobLogs)
+	mux.HandleFunc("/api/jobs/{id}/tree", s.HandleJobTree)
 	mux.HandleFunc("/api/workers", s.HandleListWorkers)
 	mux.HandleFunc("/api/dlq", s.HandleListDLQ)
+	mux.HandleFunc("/api/events", s.HandleListEvents)
+	mux.HandleFunc("/api/queues", s.HandleQueues)
+	mux.HandleFunc("/api/queues/{name}", s.HandleQueue)
 	mux.HandleFunc("/api/scheduler", s.HandleGetScheduler)
 
 	return s.corsMiddleware(mux)
@@ -48,7 +87,9 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// HandleEnqueue handles job submission
+// HandleEnqueue handles job submission. With ?wait=<duration> it blocks
+// until the job finishes, answering 200 with the finished job, or 202 with
+// its current state if the wait runs out first.
 func (s *Server) HandleEnqueue(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
 		return
@@ -58,6 +99,16 @@ func (s *Server) HandleEnqueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var wait time.Duration
+	if v := r.URL.Query().Get("wait"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			http.Error(w, "Invalid wait duration", http.StatusBadRequest)
+			return
+		}
+		wait = min(d, MaxEnqueueWait)
+	}
+
 	var job core.Job
 	if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
 		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
@@ -67,17 +118,58 @@ func (s *Server) HandleEnqueue(w http.ResponseWriter, r *http.Request) {
 	if job.Status == "" {
 		job.Status = core.StatusPending
 	}
+	if err := job.RetryPolicy.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := job.ResolveConcurrencyKey(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	if err := s.store.Enqueue(r.Context(), &job); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if wait > 0 {
+		s.waitForJob(w, r, job.ID, wait)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(job)
 }
 
+func (s *Server) waitForJob(w http.ResponseWriter, r *http.Request, jobID string, wait time.Duration) {
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+
+	job, err := s.store.WaitForJob(ctx, jobID)
+	status := http.StatusOK
+	if errors.Is(err, context.DeadlineExceeded) && r.Context().Err() == nil {
+		// Not finished yet: report the job's current state instead.
+		status = http.StatusAccepted
+		err = nil
+		if job == nil {
+			job, err = s.store.GetJob(r.Context(), jobID)
+		}
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(job)
+}
+
 // HandleListJobs represents listing jobs for the UI dashboard
 func (s *Server) HandleListJobs(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
@@ -132,8 +224,252 @@ func (s *Server) HandleGetJob(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Job not found", http.StatusNotFound)
 		return
 	}
+	if job.Status == core.StatusPending && (len(job.RequiredLabels) > 0 || job.SlotWeight() > 1) {
+		workers, err := s.liveWorkers(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		job.UnplacedReason = core.UnplacedReason(job, workers)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// HandleStreamJob sends the job as server-sent "job" events: its current
+// state, then again whenever its status or progress changes, until it
+// finishes. A job moved to the DLQ ends the stream with a "removed" event.
+func (s *Server) HandleStreamJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ctx := r.Context()
+	jobID := r.PathValue("id")
+	changed, stop, err := s.store.WatchJob(ctx, jobID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer stop()
+
+	job, err := s.store.GetJob(ctx, jobID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		if job == nil {
+			fmt.Fprint(w, "event: removed\ndata: {}\n\n")
+			flusher.Flush()
+			return
+		}
+		data, _ := json.Marshal(job)
+		fmt.Fprintf(w, "event: job\ndata: %s\n\n", data)
+		flusher.Flush()
+		if job.Status.Terminal() {
+			return
+		}
+
+	wait:
+		for {
+			select {
+			case <-changed:
+				break wait
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+				flusher.Flush()
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if job, err = s.store.GetJob(ctx, jobID); err != nil {
+			return
+		}
+	}
+}
+
+// HandleJobTree returns the job with the jobs it spawned nested under it as
+// "children", recursively. Past jobTreeLimit jobs the rest are left out and
+// "truncated" is set.
+func (s *Server) HandleJobTree(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID := r.PathValue("id")
+	jobs, err := s.store.JobTree(r.Context(), jobID, jobTreeLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tree := core.BuildJobTree(jobID, jobs)
+	if tree == nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tree":      tree,
+		"truncated": len(jobs) == jobTreeLimit,
+	})
+}
+
+// HandleJobLogs returns the job's log lines oldest first, starting after the
+// line with ID ?after= if given. With ?follow=true it instead streams them
+// as newline-delimited JSON until the job finishes.
+func (s *Server) HandleJobLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	jobID := r.PathValue("id")
+	q := r.URL.Query()
+	var after int64
+	if v := q.Get("after"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			http.Error(w, "after must be a log line ID", http.StatusBadRequest)
+			return
+		}
+		after = n
+	}
+
+	if follow, _ := strconv.ParseBool(q.Get("follow")); !follow {
+		logs, err := s.store.ListJobLogs(ctx, jobID, after, jobLogPage)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if logs == nil {
+			logs = []*core.JobLog{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"logs": logs,
+		})
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	enc := json.NewEncoder(w)
+
+	ticker := time.NewTicker(logFollowInterval)
+	defer ticker.Stop()
+	for {
+		// Workers write out a job's log before its outcome, so once the job
+		// is seen finished the lines read next are the last.
+		job, err := s.store.GetJob(ctx, jobID)
+		if err != nil {
+			return
+		}
+		for {
+			logs, err := s.store.ListJobLogs(ctx, jobID, after, jobLogPage)
+			if err != nil {
+				return
+			}
+			for _, l := range logs {
+				enc.Encode(l)
+				after = l.ID
+			}
+			if len(logs) < jobLogPage {
+				break
+			}
+		}
+		flusher.Flush()
+		if job == nil || job.Status.Terminal() {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Server) liveWorkers(ctx context.Context) ([]*core.WorkerStats, error) {
+	workers, err := s.store.ListWorkers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var live []*core.WorkerStats
+	for _, w := range workers {
+		if w.Status != "stopped" && time.Since(w.LastHeartbeat) < workerLiveness {
+			live = append(live, w)
+		}
+	}
+	return live, nil
+}
+
+// HandleCancelJob cancels a pending job or signals the worker running it
+func (s *Server) HandleCancelJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	job, err := s.store.Cancel(r.Context(), r.PathValue("id"))
+	if errors.Is(err, store.ErrNotCancellable) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if job.CancelRequested {
+		// The worker stops the job on its next heartbeat.
+		w.WriteHeader(http.StatusAccepted)
+	}
 	json.NewEncoder(w).Encode(job)
 }
 
@@ -152,10 +488,20 @@ func (s *Server) HandleListWorkers(w http.ResponseWriter, r *http.Request) {
 		workers = []*core.WorkerStats{}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	resp := map[string]interface{}{
 		"workers": workers,
-	})
+	}
+	if s.pool != nil {
+		if scaling := s.pool.ScalingStatus(); scaling != nil {
+			resp["scaling"] = scaling
+		}
+		if s.pool.Breakers != nil {
+			resp["breakers"] = s.pool.Breakers.States()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
 
 // HandleListDLQ returns dead letter jobs
@@ -179,6 +525,143 @@ func (s *Server) HandleListDLQ(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HandleListEvents returns recent events, newest first, optionally filtered
+// by ?type= and paged with ?limit= and ?offset=.
+func (s *Server) HandleListEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	limit, offset := 50, 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+
+	events, err := s.store.ListEvents(r.Context(), q.Get("type"), limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if events == nil {
+		events = []*core.Event{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"events": events,
+	})
+}
+
+// HandleQueues lists queue definitions (GET) or creates or replaces one
+// (POST).
+func (s *Server) HandleQueues(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodOptions:
+		return
+	case http.MethodGet:
+		queues, err := s.store.ListQueues(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if queues == nil {
+			queues = []*core.QueueConfig{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"queues": queues,
+		})
+	case http.MethodPost:
+		var q core.QueueConfig
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		s.saveQueue(w, r, &q)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleQueue reads (GET), replaces (PUT) or deletes (DELETE) the definition
+// of a single queue.
+func (s *Server) HandleQueue(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	switch r.Method {
+	case http.MethodOptions:
+		return
+	case http.MethodGet:
+		q, err := s.store.GetQueue(r.Context(), name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if q == nil {
+			http.Error(w, "Queue not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(q)
+	case http.MethodPut:
+		var q core.QueueConfig
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		q.Name = name
+		s.saveQueue(w, r, &q)
+	case http.MethodDelete:
+		found, err := s.store.DeleteQueue(r.Context(), name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.Error(w, "Queue not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) saveQueue(w http.ResponseWriter, r *http.Request, q *core.QueueConfig) {
+	if q.Name == "" {
+		http.Error(w, "Queue name required", http.StatusBadRequest)
+		return
+	}
+	if err := q.RetryPolicy.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.store.SaveQueue(r.Context(), q); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(q)
+}
+
 // HandleGetScheduler returns scheduler environment state
 func (s *Server) HandleGetScheduler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
@@ -0,0 +1,123 @@
+// Package config reads widad's optional JSON config file, named by
+// WIDA_CONFIG:
+//
+//	{
+//	  "queues": [
+//	    {
+//	      "name": "emails",
+//	      "max_retries": 5,
+//	      "timeout": "30s",
+//	      "priority": 10,
+//	      "retention": "168h",
+//	      "retry_policy": {"strategy": "exponential", "initial_interval": "1s", "max_interval": "5m"}
+//	    }
+//	  ]
+//	}
+//
+// Durations are Go duration strings, or nanoseconds as in the API.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+type Config struct {
+	Queues []Queue `json:"queues"`
+}
+
+// Queue mirrors core.QueueConfig with readable durations.
+type Queue struct {
+	Name        string      `json:"name"`
+	RetryPolicy RetryPolicy `json:"retry_policy"`
+	MaxRetries  int         `json:"max_retries"`
+	Timeout     Duration    `json:"timeout"`
+	Priority    int         `json:"priority"`
+	Executor    string      `json:"executor"`
+	Retention   Duration    `json:"retention"`
+}
+
+// RetryPolicy mirrors core.RetryPolicy with readable durations.
+type RetryPolicy struct {
+	InitialInterval Duration   `json:"initial_interval"`
+	MaxInterval     Duration   `json:"max_interval"`
+	MaxAttempts     int        `json:"max_attempts"`
+	Strategy        string     `json:"strategy"`
+	Multiplier      float64    `json:"multiplier"`
+	Jitter          string     `json:"jitter"`
+	Schedule        []Duration `json:"schedule"`
+}
+
+// Duration accepts "1m30s" as well as a number of nanoseconds.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("invalid duration %s", b)
+		}
+		*d = Duration(n)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Load reads and validates the config file at path.
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, q := range c.Queues {
+		if q.Name == "" {
+			return nil, fmt.Errorf("%s: queue %d has no name", path, i)
+		}
+		if err := q.QueueConfig().RetryPolicy.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: queue %s: %w", path, q.Name, err)
+		}
+	}
+	return &c, nil
+}
+
+// QueueConfig converts the definition to its core form.
+func (q Queue) QueueConfig() *core.QueueConfig {
+	schedule := make([]time.Duration, len(q.RetryPolicy.Schedule))
+	for i, d := range q.RetryPolicy.Schedule {
+		schedule[i] = time.Duration(d)
+	}
+	if len(schedule) == 0 {
+		schedule = nil
+	}
+	return &core.QueueConfig{
+		Name: q.Name,
+		RetryPolicy: core.RetryPolicy{
+			InitialInterval: time.Duration(q.RetryPolicy.InitialInterval),
+			MaxInterval:     time.Duration(q.RetryPolicy.MaxInterval),
+			MaxAttempts:     q.RetryPolicy.MaxAttempts,
+			Strategy:        q.RetryPolicy.Strategy,
+			Multiplier:      q.RetryPolicy.Multiplier,
+			Jitter:          q.RetryPolicy.Jitter,
+			Schedule:        schedule,
+		},
+		MaxRetries: q.MaxRetries,
+		Timeout:    time.Duration(q.Timeout),
+		Priority:   q.Priority,
+		Executor:   q.Executor,
+		Retention:  time.Duration(q.Retention),
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wida.json")
+	os.WriteFile(path, []byte(`{
+		"queues": [{
+			"name": "emails",
+			"max_retries": 5,
+			"timeout": "30s",
+			"retention": 3600000000000,
+			"retry_policy": {"strategy": "schedule", "schedule": ["1s", "1m"]}
+		}]
+	}`), 0o644)
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := c.Queues[0].QueueConfig()
+	if q.Name != "emails" || q.MaxRetries != 5 || q.Timeout != 30*time.Second || q.Retention != time.Hour {
+		t.Errorf("queue = %+v", q)
+	}
+	if q.RetryPolicy.Strategy != core.BackoffSchedule || len(q.RetryPolicy.Schedule) != 2 || q.RetryPolicy.Schedule[1] != time.Minute {
+		t.Errorf("retry policy = %+v", q.RetryPolicy)
+	}
+
+	os.WriteFile(path, []byte(`{"queues": [{"name": "bad", "retry_policy": {"strategy": "schedule"}}]}`), 0o644)
+	if _, err := Load(path); err == nil {
+		t.Error("schedule strategy without a schedule was accepted")
+	}
+}
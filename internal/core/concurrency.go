@@ -0,0 +1,58 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ResolveConcurrencyKey fills in ConcurrencyKey from the payload when a
+// ConcurrencyKeyPath is given, and defaults the limit to 1 for keyed jobs.
+// Derived keys are prefixed with their path so that, say, tenant 42 and
+// customer 42 do not share a limit.
+func (j *Job) ResolveConcurrencyKey() error {
+	if j.ConcurrencyKey == "" && j.ConcurrencyKeyPath != "" {
+		value, err := LookupPath(j.Payload, j.ConcurrencyKeyPath)
+		if err != nil {
+			return fmt.Errorf("concurrency key: %w", err)
+		}
+		j.ConcurrencyKey = strings.TrimPrefix(j.ConcurrencyKeyPath, "$.") + "=" + value
+	}
+	if j.ConcurrencyKey != "" && j.ConcurrencyLimit <= 0 {
+		j.ConcurrencyLimit = 1
+	}
+	return nil
+}
+
+// LookupPath walks a dotted path ("a.b.c", optionally prefixed with "$.")
+// through a JSON object and returns the scalar found there as a string.
+func LookupPath(payload json.RawMessage, path string) (string, error) {
+	var node interface{}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&node); err != nil {
+		return "", fmt.Errorf("payload is not valid JSON: %w", err)
+	}
+
+	for _, part := range strings.Split(strings.TrimPrefix(path, "$."), ".") {
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("path %q not found in payload", path)
+		}
+		if node, ok = obj[part]; !ok {
+			return "", fmt.Errorf("path %q not found in payload", path)
+		}
+	}
+
+	switch v := node.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("path %q does not point to a scalar value", path)
+	}
+}
@@ -0,0 +1,35 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestResolveConcurrencyKey(t *testing.T) {
+	job := &Job{
+		Payload:            json.RawMessage(`{"customer": {"id": 12345678901}, "tenant_id": "acme"}`),
+		ConcurrencyKeyPath: "$.customer.id",
+	}
+	if err := job.ResolveConcurrencyKey(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if job.ConcurrencyKey != "customer.id=12345678901" {
+		t.Errorf("Expected key customer.id=12345678901, got %q", job.ConcurrencyKey)
+	}
+	if job.ConcurrencyLimit != 1 {
+		t.Errorf("Expected default limit 1, got %d", job.ConcurrencyLimit)
+	}
+
+	explicit := &Job{ConcurrencyKey: "tenant-a", ConcurrencyKeyPath: "tenant_id", ConcurrencyLimit: 3}
+	if err := explicit.ResolveConcurrencyKey(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if explicit.ConcurrencyKey != "tenant-a" || explicit.ConcurrencyLimit != 3 {
+		t.Errorf("Expected explicit key to be kept, got %q/%d", explicit.ConcurrencyKey, explicit.ConcurrencyLimit)
+	}
+
+	missing := &Job{Payload: json.RawMessage(`{"tenant_id": "acme"}`), ConcurrencyKeyPath: "customer_id"}
+	if err := missing.ResolveConcurrencyKey(); err == nil {
+		t.Error("Expected an error for a path missing from the payload")
+	}
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+)
+
+type (
+	attemptKey    struct{}
+	tenantKey     struct{}
+	jobContextKey struct{}
+)
+
+// JobContext lets a running job report on itself. The worker pool attaches
+// one to the context every job executes with.
+type JobContext interface {
+	// ReportProgress records how far the job has got, percent being
+	// between 0 and 100. Calling it every few seconds is plenty.
+	ReportProgress(percent float64, message string) error
+	// SaveCheckpoint stores an opaque blob from which a later attempt can
+	// resume, replacing any earlier one.
+	SaveCheckpoint(data []byte) error
+	// Checkpoint returns the last checkpoint saved by this or an earlier
+	// attempt, or nil.
+	Checkpoint() []byte
+	// Logger writes to the job's log, tagged with the attempt number.
+	Logger() *slog.Logger
+	// Spawn enqueues child jobs, with ParentID set to the running job, in
+	// the same transaction that marks it successful. They are dropped if
+	// the attempt does not succeed. Children without an ID are given
+	// "<parent-id>.<n>", and without a queue or executor take the parent's.
+	Spawn(children ...*Job) error
+	// ContinueWith enqueues next once every child spawned by this attempt
+	// has succeeded, or straight away if there are none. A later call
+	// replaces it. Without an ID it is given "<parent-id>.then".
+	ContinueWith(next *Job) error
+}
+
+// WithAttempt attaches the attempt being executed to ctx, so executors can
+// record details such as an exit code on it.
+func WithAttempt(ctx context.Context, attempt *Attempt) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// AttemptFromContext returns the attempt attached by WithAttempt, or nil.
+func AttemptFromContext(ctx context.Context) *Attempt {
+	attempt, _ := ctx.Value(attemptKey{}).(*Attempt)
+	return attempt
+}
+
+// WithTenant attaches the tenant a job runs on behalf of to ctx.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant attached by WithTenant, or "".
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// WithJobContext attaches jc to ctx.
+func WithJobContext(ctx context.Context, jc JobContext) context.Context {
+	return context.WithValue(ctx, jobContextKey{}, jc)
+}
+
+// JobContextFromContext returns the JobContext attached by WithJobContext,
+// or nil.
+func JobContextFromContext(ctx context.Context) JobContext {
+	jc, _ := ctx.Value(jobContextKey{}).(JobContext)
+	return jc
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PermanentError marks a failure that retrying cannot fix. The worker pool
+// moves the job straight to the DLQ.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Permanent wraps err so the job is not retried.
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// RetryAfterError asks for the next attempt after Delay instead of the
+// retry policy's backoff.
+type RetryAfterError struct {
+	Delay time.Duration
+	Err   error
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%v (retry after %v)", e.Err, e.Delay)
+}
+func (e *RetryAfterError) Unwrap() error { return e.Err }
+
+// RetryAfter wraps err so the next attempt runs after d.
+func RetryAfter(d time.Duration, err error) error {
+	return &RetryAfterError{Delay: d, Err: err}
+}
+
+// SnoozeError reschedules the job after Delay. Unlike a failure, it does not
+// record an attempt or use up a retry.
+type SnoozeError struct {
+	Delay time.Duration
+}
+
+func (e *SnoozeError) Error() string { return fmt.Sprintf("snoozed for %v", e.Delay) }
+
+// Snooze asks for the job to run again after d, as if it had not run yet.
+func Snooze(d time.Duration) error {
+	return &SnoozeError{Delay: d}
+}
+
+// DiscardError drops the job: it ends as discarded, with no retries and no
+// DLQ entry.
+type DiscardError struct {
+	Err error
+}
+
+func (e *DiscardError) Error() string { return e.Err.Error() }
+func (e *DiscardError) Unwrap() error { return e.Err }
+
+// Discard wraps err so the job is dropped instead of retried or dead-lettered.
+func Discard(err error) error {
+	return &DiscardError{Err: err}
+}
+
+// PanicError is a panic recovered from an executor.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string { return fmt.Sprintf("panic: %v", e.Value) }
+
+// IsPermanent reports whether err, or any error it wraps, is permanent.
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// IsDiscard reports whether err, or any error it wraps, discards the job.
+func IsDiscard(err error) bool {
+	var discard *DiscardError
+	return errors.As(err, &discard)
+}
+
+// SnoozeDelay returns the delay requested by a SnoozeError in err's chain.
+func SnoozeDelay(err error) (time.Duration, bool) {
+	var snooze *SnoozeError
+	if errors.As(err, &snooze) {
+		return snooze.Delay, true
+	}
+	return 0, false
+}
+
+// RetryAfterDelay returns the delay requested by a RetryAfterError in err's
+// chain.
+func RetryAfterDelay(err error) (time.Duration, bool) {
+	var retryAfter *RetryAfterError
+	if errors.As(err, &retryAfter) {
+		return retryAfter.Delay, true
+	}
+	return 0, false
+}
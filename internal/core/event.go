@@ -0,0 +1,24 @@
+package core
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event types.
+const (
+	// EventBreaker records a circuit breaker changing state. Its subject is
+	// the breaker's key, e.g. "queue:emails" or "executor:http".
+	EventBreaker = "breaker"
+)
+
+// Event is a notable change in the cluster, kept for operators to inspect.
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	Subject   string          `json:"subject"`
+	Message   string          `json:"message"`
+	Node      string          `json:"node,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
@@ -2,6 +2,8 @@ package core
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 )
 
@@ -13,24 +15,149 @@ const (
 	StatusSuccess Status = "success"
 	StatusFailed  Status = "failed"
 	StatusDead    Status = "dead"
+
+	StatusCancelled Status = "cancelled"
+	// StatusDiscarded ends a job whose executor returned a Discard error.
+	StatusDiscarded Status = "discarded"
+
+	// StatusTimeout only appears on attempts that overran Job.Timeout.
+	StatusTimeout Status = "timeout"
+	// StatusInterrupted marks attempts cut short by a worker shutting down.
+	// They do not count against the job's retries.
+	StatusInterrupted Status = "interrupted"
+)
+
+// Terminal reports whether a job in this status will never run again.
+func (s Status) Terminal() bool {
+	switch s {
+	case StatusSuccess, StatusDead, StatusCancelled, StatusDiscarded:
+		return true
+	}
+	return false
+}
+
+// DependencyFailure returns why a job waiting on depID can never run now
+// that depID ended in status, or "" if the job may still run. A fan-in
+// continuation whose child died fails this way rather than wait forever.
+func DependencyFailure(depID string, status Status) string {
+	switch status {
+	case StatusDead, StatusCancelled, StatusDiscarded:
+		return fmt.Sprintf("dependency %s ended as %s", depID, status)
+	}
+	return ""
+}
+
+var (
+	// ErrCancelled is the cause attached to an execution context when the
+	// job was cancelled through the API.
+	ErrCancelled = errors.New("job cancelled")
+	// ErrTimeout is the cause attached when the job overran its Timeout.
+	ErrTimeout = errors.New("job timed out")
+	// ErrInterrupted is the cause attached when the worker shuts down before
+	// the job finishes.
+	ErrInterrupted = errors.New("job interrupted by worker shutdown")
 )
 
+// DefaultExecutor is the executor a job runs on when it names none.
+const DefaultExecutor = "default"
+
+// NoRetries, as a job's MaxRetries, stops its queue's default applying:
+// the job is not retried. It is stored as 0.
+const NoRetries = -1
+
 type Job struct {
 	ID      string          `json:"id"`
 	Queue   string          `json:"queue"`
 	Payload json.RawMessage `json:"payload"`
 	Status  Status          `json:"status"`
 
+	// Type identifies the kind of work, letting one executor dispatch to
+	// per-type handlers.
+	Type string `json:"type,omitempty"`
+
+	// Executor names the registered executor that runs the job, with
+	// ExecutorConfig holding its executor-specific settings.
+	Executor       string          `json:"executor,omitempty"`
+	ExecutorConfig json.RawMessage `json:"executor_config,omitempty"`
+
 	RunAt       *time.Time  `json:"run_at,omitempty"`
 	CronExpr    string      `json:"cron_expr,omitempty"`
 	RetryPolicy RetryPolicy `json:"retry_policy"`
 
-	Timeout    time.Duration `json:"timeout"`
-	MaxRetries int           `json:"max_retries"`
-	Attempts   []Attempt     `json:"attempts"`
+	Timeout time.Duration `json:"timeout"`
+	// MaxRetries of 0 takes the queue's default; NoRetries opts out of it.
+	MaxRetries int       `json:"max_retries"`
+	Attempts   []Attempt `json:"attempts"`
+
+	// Priority orders ready jobs: higher runs first.
+	Priority int `json:"priority,omitempty"`
+
+	// Weight is how many of a worker's slots the job occupies while it
+	// runs, 1 if unset.
+	Weight int `json:"weight,omitempty"`
 
 	Dependencies []string `json:"dependencies,omitempty"`
 	Dependents   []string `json:"dependents,omitempty"`
+
+	// ParentID is the job that spawned this one while it ran.
+	ParentID string `json:"parent_id,omitempty"`
+
+	// ConcurrencyKey caps how many jobs sharing the key may run at once.
+	// It can be set explicitly or derived from the payload via
+	// ConcurrencyKeyPath (e.g. "tenant_id" or "customer.id").
+	ConcurrencyKey     string `json:"concurrency_key,omitempty"`
+	ConcurrencyKeyPath string `json:"concurrency_key_path,omitempty"`
+	ConcurrencyLimit   int    `json:"concurrency_limit,omitempty"`
+
+	// GroupKey orders jobs strictly: within a group only the oldest
+	// unfinished job can be claimed, so the next one starts only after the
+	// previous one succeeds or dies.
+	GroupKey string `json:"group_key,omitempty"`
+
+	// RequiredLabels restricts the job to workers with all of these labels.
+	// PreferredLabels lets matching workers claim it first; others take it
+	// only once it has waited a while.
+	RequiredLabels  Labels `json:"required_labels,omitempty"`
+	PreferredLabels Labels `json:"preferred_labels,omitempty"`
+
+	// UnplacedReason explains why a pending job has no compatible worker.
+	// It is filled in when a single job is fetched, not stored.
+	UnplacedReason string `json:"unplaced_reason,omitempty"`
+
+	// WorkerID and StartedAt record the worker that last claimed the job,
+	// and when.
+	WorkerID  string     `json:"worker_id,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+
+	// CancelRequested is set when a running job has been asked to stop.
+	CancelRequested bool `json:"cancel_requested,omitempty"`
+
+	// Progress is the latest progress the job reported while running.
+	// Checkpoint is the last state it saved, handed to the next attempt so
+	// it can resume; it is only loaded when the job is claimed.
+	Progress   *Progress `json:"progress,omitempty"`
+	Checkpoint []byte    `json:"-"`
+
+	// Result holds the executor's output once the job succeeds. With a
+	// ResultTTL it is purged at ResultExpiresAt.
+	Result          json.RawMessage `json:"result,omitempty"`
+	ResultTTL       time.Duration   `json:"result_ttl,omitempty"`
+	ResultExpiresAt *time.Time      `json:"result_expires_at,omitempty"`
+}
+
+// Progress is how far a running job has got, as reported by its executor.
+type Progress struct {
+	Percent   float64   `json:"percent"`
+	Message   string    `json:"message,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SlotWeight is the job's Weight, at least 1.
+func (j *Job) SlotWeight() int {
+	if j.Weight < 1 {
+		return 1
+	}
+	return j.Weight
 }
 
 type Attempt struct {
@@ -38,18 +165,47 @@ type Attempt struct {
 	FinishedAt time.Time `json:"finished_at,omitempty"`
 	Status     Status    `json:"status"`
 	Error      string    `json:"error,omitempty"`
+
+	// Set by executors that run a process.
+	ExitCode *int           `json:"exit_code,omitempty"`
+	Usage    *ResourceUsage `json:"usage,omitempty"`
+
+	// Stack is the executor's stack trace if the attempt panicked.
+	Stack string `json:"stack,omitempty"`
+}
+
+// ResourceUsage is the CPU and memory a job's process consumed.
+type ResourceUsage struct {
+	UserTime   time.Duration `json:"user_time"`
+	SystemTime time.Duration `json:"system_time"`
+	MaxRSSKB   int64         `json:"max_rss_kb,omitempty"`
 }
 
 type RetryPolicy struct {
 	InitialInterval time.Duration `json:"initial_interval"`
 	MaxInterval     time.Duration `json:"max_interval"`
 	MaxAttempts     int           `json:"max_attempts"`
+
+	// Strategy names the backoff: exponential (the default), fixed,
+	// linear, decorrelated, schedule, or one added with RegisterBackoff.
+	Strategy string `json:"strategy,omitempty"`
+	// Multiplier is the exponential growth factor, 2 if unset.
+	Multiplier float64 `json:"multiplier,omitempty"`
+	// Jitter is additive (the default), none, full or equal.
+	Jitter string `json:"jitter,omitempty"`
+	// Schedule lists the delays for the schedule strategy. The last one
+	// repeats.
+	Schedule []time.Duration `json:"schedule,omitempty"`
 }
 
 type WorkerStats struct {
 	ID            string    `json:"id"`
 	Status        string    `json:"status"`
 	CurrentJobID  string    `json:"current_job_id,omitempty"`
+	Labels        Labels    `json:"labels,omitempty"`
+	Capacity      int       `json:"capacity"`
+	ActiveJobs    int       `json:"active_jobs"`
+	UsedSlots     int       `json:"used_slots"`
 	JobsCompleted int       `json:"jobs_completed"`
 	LastHeartbeat time.Time `json:"last_heartbeat"`
 }
@@ -0,0 +1,20 @@
+package core
+
+import "testing"
+
+func TestDependencyFailure(t *testing.T) {
+	// A continuation waiting on a child that went to the DLQ fails.
+	if got, want := DependencyFailure("crawl.2", StatusDead), "dependency crawl.2 ended as dead"; got != want {
+		t.Errorf("dead child: %q, want %q", got, want)
+	}
+	for _, status := range []Status{StatusCancelled, StatusDiscarded} {
+		if DependencyFailure("crawl.2", status) == "" {
+			t.Errorf("%s child does not fail the continuation", status)
+		}
+	}
+	for _, status := range []Status{StatusPending, StatusRunning, StatusSuccess} {
+		if got := DependencyFailure("crawl.2", status); got != "" {
+			t.Errorf("%s child fails the continuation: %q", status, got)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package core
+
+import "time"
+
+// JobLog is a line a job logged through its JobContext's logger.
+type JobLog struct {
+	ID      int64          `json:"id"`
+	JobID   string         `json:"job_id"`
+	Attempt int            `json:"attempt"`
+	Level   string         `json:"level"`
+	Message string         `json:"message"`
+	Fields  map[string]any `json:"fields,omitempty"`
+	Time    time.Time      `json:"time"`
+}
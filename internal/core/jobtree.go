@@ -0,0 +1,25 @@
+package core
+
+// JobTree is a job together with the jobs it spawned, and theirs.
+type JobTree struct {
+	*Job
+	Children []*JobTree `json:"children,omitempty"`
+}
+
+// BuildJobTree links jobs by ParentID into the tree rooted at rootID. Jobs
+// not under the root are left out; it returns nil if the root is missing.
+func BuildJobTree(rootID string, jobs []*Job) *JobTree {
+	nodes := make(map[string]*JobTree, len(jobs))
+	for _, job := range jobs {
+		nodes[job.ID] = &JobTree{Job: job}
+	}
+	for _, job := range jobs {
+		if job.ID == rootID {
+			continue
+		}
+		if parent, ok := nodes[job.ParentID]; ok {
+			parent.Children = append(parent.Children, nodes[job.ID])
+		}
+	}
+	return nodes[rootID]
+}
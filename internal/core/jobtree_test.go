@@ -0,0 +1,31 @@
+package core
+
+import "testing"
+
+func TestBuildJobTree(t *testing.T) {
+	jobs := []*Job{
+		{ID: "crawl", ParentID: "site"},
+		{ID: "crawl.1", ParentID: "crawl"},
+		{ID: "crawl.2", ParentID: "crawl"},
+		{ID: "crawl.1.1", ParentID: "crawl.1"},
+		{ID: "crawl.then", ParentID: "crawl"},
+		{ID: "unrelated", ParentID: "other"},
+	}
+	tree := BuildJobTree("crawl", jobs)
+	if tree == nil || tree.ID != "crawl" {
+		t.Fatalf("root = %+v", tree)
+	}
+	var ids []string
+	for _, c := range tree.Children {
+		ids = append(ids, c.ID)
+	}
+	if len(ids) != 3 || ids[0] != "crawl.1" || ids[1] != "crawl.2" || ids[2] != "crawl.then" {
+		t.Errorf("children = %v", ids)
+	}
+	if got := tree.Children[0].Children; len(got) != 1 || got[0].ID != "crawl.1.1" {
+		t.Errorf("grandchildren = %v", got)
+	}
+	if BuildJobTree("missing", jobs) != nil {
+		t.Error("tree built without its root")
+	}
+}
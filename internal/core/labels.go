@@ -0,0 +1,90 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Labels are key=value attributes a worker advertises, such as zone=a or
+// gpu=true, and that jobs select workers by.
+type Labels map[string]string
+
+// ParseLabels reads labels written as "gpu=false,zone=a".
+func ParseLabels(s string) (Labels, error) {
+	labels := Labels{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q, want key=value", part)
+		}
+		labels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
+
+// Matches reports whether l has every label in selector.
+func (l Labels) Matches(selector Labels) bool {
+	for k, v := range selector {
+		if have, ok := l[k]; !ok || have != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (l Labels) String() string {
+	pairs := make([]string, 0, len(l))
+	for k, v := range l {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// UnplacedReason explains why none of workers can take a job that requires
+// labels, or returns "" if one can. Only live workers should be passed.
+func UnplacedReason(job *Job, workers []*WorkerStats) string {
+	weight := job.SlotWeight()
+	if len(job.RequiredLabels) == 0 && weight == 1 {
+		return ""
+	}
+	if len(workers) == 0 {
+		return "no workers are running"
+	}
+
+	// Report the label that rules out the most workers, or failing that the
+	// weight.
+	missing := make(map[string]int)
+	matched, largest := 0, 0
+	for _, w := range workers {
+		if w.Labels.Matches(job.RequiredLabels) {
+			if weight == 1 || w.Capacity >= weight {
+				return ""
+			}
+			matched++
+			largest = max(largest, w.Capacity)
+			continue
+		}
+		for k, v := range job.RequiredLabels {
+			if w.Labels[k] != v {
+				missing[k+"="+v]++
+			}
+		}
+	}
+	if matched > 0 {
+		return fmt.Sprintf("job weight %d exceeds every eligible worker's capacity (largest %d)", weight, largest)
+	}
+	var worst string
+	for label, n := range missing {
+		if n > missing[worst] || (n == missing[worst] && label < worst) {
+			worst = label
+		}
+	}
+	return fmt.Sprintf("no live worker has labels %s (%d of %d lack %s)",
+		job.RequiredLabels, missing[worst], len(workers), worst)
+}
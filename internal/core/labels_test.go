@@ -0,0 +1,45 @@
+package core
+
+import "testing"
+
+func TestParseLabels(t *testing.T) {
+	labels, err := ParseLabels("gpu=false, zone=a,mem=high")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := labels.String(); got != "gpu=false,mem=high,zone=a" {
+		t.Errorf("labels = %s", got)
+	}
+	if _, err := ParseLabels("gpu"); err == nil {
+		t.Error("label without a value accepted")
+	}
+}
+
+func TestUnplacedReason(t *testing.T) {
+	workers := []*WorkerStats{
+		{ID: "w1", Labels: Labels{"zone": "a", "gpu": "false"}},
+		{ID: "w2", Labels: Labels{"zone": "b", "gpu": "false"}},
+	}
+
+	job := &Job{RequiredLabels: Labels{"zone": "b"}}
+	if reason := UnplacedReason(job, workers); reason != "" {
+		t.Errorf("placeable job reported unplaced: %s", reason)
+	}
+
+	job = &Job{RequiredLabels: Labels{"zone": "b", "gpu": "true"}}
+	want := "no live worker has labels gpu=true,zone=b (2 of 2 lack gpu=true)"
+	if reason := UnplacedReason(job, workers); reason != want {
+		t.Errorf("reason = %q, want %q", reason, want)
+	}
+
+	if reason := UnplacedReason(job, nil); reason != "no workers are running" {
+		t.Errorf("reason without workers = %q", reason)
+	}
+
+	workers[0].Capacity, workers[1].Capacity = 8, 2
+	job = &Job{RequiredLabels: Labels{"zone": "b"}, Weight: 4}
+	want = "job weight 4 exceeds every eligible worker's capacity (largest 2)"
+	if reason := UnplacedReason(job, workers); reason != want {
+		t.Errorf("reason = %q, want %q", reason, want)
+	}
+}
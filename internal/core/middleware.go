@@ -0,0 +1,26 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// ExecutorFunc lets an ordinary function serve as an Executor.
+type ExecutorFunc func(ctx context.Context, job *Job) (json.RawMessage, error)
+
+func (f ExecutorFunc) Execute(ctx context.Context, job *Job) (json.RawMessage, error) {
+	return f(ctx, job)
+}
+
+// Middleware wraps an executor with behavior that runs around every
+// execution, such as logging or metrics.
+type Middleware func(Executor) Executor
+
+// Chain wraps e in mws. The first middleware is the outermost, so it sees
+// the job first and the result last.
+func Chain(e Executor, mws ...Middleware) Executor {
+	for i := len(mws) - 1; i >= 0; i-- {
+		e = mws[i](e)
+	}
+	return e
+}
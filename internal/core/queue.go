@@ -0,0 +1,45 @@
+package core
+
+import "time"
+
+// QueueConfig defines a queue: the defaults for jobs enqueued on it. Zero
+// fields set no default.
+type QueueConfig struct {
+	Name        string        `json:"name"`
+	RetryPolicy RetryPolicy   `json:"retry_policy"`
+	MaxRetries  int           `json:"max_retries,omitempty"`
+	Timeout     time.Duration `json:"timeout,omitempty"`
+	Priority    int           `json:"priority,omitempty"`
+	Executor    string        `json:"executor,omitempty"`
+
+	// Retention is how long finished jobs are kept before they are deleted.
+	Retention time.Duration `json:"retention,omitempty"`
+}
+
+// ApplyDefaults fills in the settings job leaves unset from the queue's
+// defaults. Anything set on the job wins.
+func (q *QueueConfig) ApplyDefaults(job *Job) {
+	if job.RetryPolicy.IsZero() {
+		job.RetryPolicy = q.RetryPolicy
+	}
+	if job.MaxRetries == 0 {
+		job.MaxRetries = q.MaxRetries
+	} else if job.MaxRetries == NoRetries {
+		job.MaxRetries = 0
+	}
+	if job.Timeout == 0 {
+		job.Timeout = q.Timeout
+	}
+	if job.Priority == 0 {
+		job.Priority = q.Priority
+	}
+	if job.Executor == "" {
+		job.Executor = q.Executor
+	}
+}
+
+// IsZero reports whether the policy was left unset.
+func (p RetryPolicy) IsZero() bool {
+	return p.InitialInterval == 0 && p.MaxInterval == 0 && p.MaxAttempts == 0 &&
+		p.Strategy == "" && p.Multiplier == 0 && p.Jitter == "" && len(p.Schedule) == 0
+}
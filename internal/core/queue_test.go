@@ -0,0 +1,45 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueueConfigApplyDefaults(t *testing.T) {
+	q := &QueueConfig{
+		Name:        "emails",
+		RetryPolicy: RetryPolicy{InitialInterval: time.Second, MaxInterval: time.Minute},
+		MaxRetries:  5,
+		Timeout:     30 * time.Second,
+		Priority:    10,
+		Executor:    "http",
+	}
+
+	job := &Job{Queue: "emails"}
+	q.ApplyDefaults(job)
+	if job.RetryPolicy.InitialInterval != time.Second || job.MaxRetries != 5 || job.Timeout != 30*time.Second ||
+		job.Priority != 10 || job.Executor != "http" {
+		t.Errorf("defaults not applied: %+v", job)
+	}
+
+	job = &Job{
+		Queue:       "emails",
+		RetryPolicy: RetryPolicy{Strategy: BackoffFixed, InitialInterval: 5 * time.Second},
+		MaxRetries:  1,
+		Timeout:     time.Second,
+		Executor:    "subprocess",
+	}
+	q.ApplyDefaults(job)
+	if job.RetryPolicy.Strategy != BackoffFixed || job.MaxRetries != 1 || job.Timeout != time.Second || job.Executor != "subprocess" {
+		t.Errorf("job overrides replaced by queue defaults: %+v", job)
+	}
+	if job.Priority != 10 {
+		t.Errorf("priority = %d, want the queue default 10", job.Priority)
+	}
+
+	job = &Job{Queue: "emails", MaxRetries: NoRetries}
+	q.ApplyDefaults(job)
+	if job.MaxRetries != 0 {
+		t.Errorf("job opting out of retries got MaxRetries %d", job.MaxRetries)
+	}
+}
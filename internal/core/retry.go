@@ -1,30 +1,222 @@
 package core
 
 import (
+	"fmt"
+	"log"
 	"math"
 	"math/rand"
+	"sync"
 	"time"
 )
 
-// CalculateRetryDelay uses exponential backoff with jitter
+// NextRetry decides what follows a failed attempt, which must already be
+// appended to job.Attempts. It returns when to run the job next, or false
+// if the failure was permanent or the retries are used up and the job
+// belongs in the DLQ. A RetryAfterError overrides the policy's backoff.
+func NextRetry(job *Job, err error, now time.Time) (time.Time, bool) {
+	attempts := countedAttempts(job.Attempts)
+	if IsPermanent(err) || attempts >= job.MaxRetries {
+		return time.Time{}, false
+	}
+	delay, ok := RetryAfterDelay(err)
+	if !ok {
+		delay = CalculateRetryDelay(attempts, job.RetryPolicy)
+	}
+	return now.Add(delay), true
+}
+
+// countedAttempts is the number of attempts that used up a retry.
+func countedAttempts(attempts []Attempt) int {
+	n := 0
+	for _, a := range attempts {
+		if a.Status != StatusInterrupted {
+			n++
+		}
+	}
+	return n
+}
+
+// Backoff strategies, selected by RetryPolicy.Strategy.
+const (
+	BackoffExponential  = "exponential"
+	BackoffFixed        = "fixed"
+	BackoffLinear       = "linear"
+	BackoffDecorrelated = "decorrelated"
+	BackoffSchedule     = "schedule"
+)
+
+// Jitter modes, selected by RetryPolicy.Jitter.
+const (
+	JitterAdditive = "additive" // adds up to 50% of the delay
+	JitterNone     = "none"
+	JitterFull     = "full"  // anywhere between 0 and the delay
+	JitterEqual    = "equal" // between half the delay and the delay
+)
+
+// BackoffFunc computes the delay before retry number attempt (1-based),
+// before jitter and the MaxInterval cap. rng is never nil.
+type BackoffFunc func(attempt int, policy RetryPolicy, rng *rand.Rand) time.Duration
+
+var (
+	backoffMu sync.RWMutex
+	backoffs  = map[string]BackoffFunc{
+		BackoffExponential: exponentialBackoff,
+		BackoffFixed:       fixedBackoff,
+		BackoffLinear:      linearBackoff,
+		BackoffSchedule:    scheduleBackoff,
+	}
+)
+
+// RegisterBackoff makes a custom strategy available to retry policies under
+// name. It must be registered in every process that runs workers or accepts
+// jobs, since policies naming an unknown strategy are rejected.
+func RegisterBackoff(name string, fn BackoffFunc) {
+	backoffMu.Lock()
+	defer backoffMu.Unlock()
+	backoffs[name] = fn
+}
+
+// Validate checks that the policy's strategy is known and its
+// strategy-specific settings.
+func (p RetryPolicy) Validate() error {
+	if p.Strategy != "" && p.Strategy != BackoffDecorrelated {
+		backoffMu.RLock()
+		_, ok := backoffs[p.Strategy]
+		backoffMu.RUnlock()
+		if !ok {
+			return fmt.Errorf("unknown retry strategy %q", p.Strategy)
+		}
+	}
+	switch p.Jitter {
+	case "", JitterAdditive, JitterNone, JitterFull, JitterEqual:
+	default:
+		return fmt.Errorf("unknown retry jitter %q", p.Jitter)
+	}
+	if p.Multiplier < 0 {
+		return fmt.Errorf("retry multiplier must not be negative")
+	}
+	if p.Strategy == BackoffSchedule && len(p.Schedule) == 0 {
+		return fmt.Errorf("retry strategy %q needs a schedule", BackoffSchedule)
+	}
+	return nil
+}
+
+// CalculateRetryDelay returns the delay before retry number attempt, using
+// the global random source for jitter.
 func CalculateRetryDelay(attempt int, policy RetryPolicy) time.Duration {
+	return policy.Delay(attempt, nil)
+}
+
+// Delay returns the delay before retry number attempt. Jitter is drawn from
+// rng, or the global random source if rng is nil; a seeded rng makes the
+// result reproducible.
+func (p RetryPolicy) Delay(attempt int, rng *rand.Rand) time.Duration {
 	if attempt <= 0 {
 		return 0
 	}
+	if rng == nil {
+		rng = rand.New(globalSource{})
+	}
+
+	if p.Strategy == BackoffDecorrelated {
+		// Random by construction, so no further jitter.
+		return decorrelatedBackoff(attempt, p, rng)
+	}
 
-	delay := policy.InitialInterval * time.Duration(math.Pow(2, float64(attempt-1)))
-	if policy.MaxInterval > 0 && delay > policy.MaxInterval {
-		delay = policy.MaxInterval
+	strategy := p.Strategy
+	if strategy == "" {
+		strategy = BackoffExponential
+	}
+	backoffMu.RLock()
+	fn, ok := backoffs[strategy]
+	backoffMu.RUnlock()
+	if !ok {
+		log.Printf("Unknown retry strategy %q, using %s\n", strategy, BackoffExponential)
+		fn = exponentialBackoff
 	}
 
-	// Add jitter (up to 50% of the delay)
-	if delay > 0 {
-		jitterMax := int64(delay) / 2
-		if jitterMax > 0 {
-			jitter := time.Duration(rand.Int63n(jitterMax))
-			delay = delay + jitter
+	delay := fn(attempt, p, rng)
+	if p.MaxInterval > 0 && delay > p.MaxInterval {
+		delay = p.MaxInterval
+	}
+	return applyJitter(delay, p.Jitter, rng)
+}
+
+func applyJitter(delay time.Duration, mode string, rng *rand.Rand) time.Duration {
+	if delay <= 0 {
+		return delay
+	}
+	switch mode {
+	case JitterNone:
+		return delay
+	case JitterFull:
+		return time.Duration(rng.Int63n(int64(delay)))
+	case JitterEqual:
+		half := int64(delay) / 2
+		return time.Duration(half + rng.Int63n(int64(delay)-half))
+	default:
+		// Add jitter (up to 50% of the delay)
+		if jitterMax := int64(delay) / 2; jitterMax > 0 {
+			delay += time.Duration(rng.Int63n(jitterMax))
 		}
+		return delay
 	}
+}
 
-	return delay
+func exponentialBackoff(attempt int, p RetryPolicy, _ *rand.Rand) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	delay := float64(p.InitialInterval) * math.Pow(multiplier, float64(attempt-1))
+	if delay >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
 }
+
+func fixedBackoff(_ int, p RetryPolicy, _ *rand.Rand) time.Duration {
+	return p.InitialInterval
+}
+
+func linearBackoff(attempt int, p RetryPolicy, _ *rand.Rand) time.Duration {
+	return p.InitialInterval * time.Duration(attempt)
+}
+
+// scheduleBackoff reads the delay from p.Schedule, repeating its last entry
+// once the schedule runs out.
+func scheduleBackoff(attempt int, p RetryPolicy, _ *rand.Rand) time.Duration {
+	if len(p.Schedule) == 0 {
+		return p.InitialInterval
+	}
+	return p.Schedule[min(attempt, len(p.Schedule))-1]
+}
+
+// decorrelatedBackoff draws each delay between InitialInterval and three
+// times the previous one, capped at MaxInterval. No state is kept between
+// retries, so the chain is replayed from the first retry.
+func decorrelatedBackoff(attempt int, p RetryPolicy, rng *rand.Rand) time.Duration {
+	base := int64(p.InitialInterval)
+	if base <= 0 {
+		return 0
+	}
+	delay := base
+	for i := 1; i <= attempt; i++ {
+		upper := int64(math.MaxInt64)
+		if delay < upper/3 {
+			upper = delay * 3
+		}
+		delay = base + rng.Int63n(upper-base+1)
+		if p.MaxInterval > 0 && delay > int64(p.MaxInterval) {
+			delay = int64(p.MaxInterval)
+		}
+	}
+	return time.Duration(delay)
+}
+
+// globalSource draws from the global math/rand source, which is safe for
+// concurrent use unlike a rand.Rand of our own.
+type globalSource struct{}
+
+func (globalSource) Int63() int64 { return rand.Int63() }
+func (globalSource) Seed(int64)   {}
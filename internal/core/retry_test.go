@@ -1,6 +1,9 @@
 package core
 
 import (
+	"encoding/json"
+	"errors"
+	"math/rand"
 	"testing"
 	"time"
 )
@@ -28,3 +31,100 @@ func TestCalculateRetryDelay(t *testing.T) {
 		t.Errorf("Expected delay10 bounded by MaxInterval+Jitter (10s-15s), got %v", delay10)
 	}
 }
+
+func TestNextRetrySkipsInterruptedAttempts(t *testing.T) {
+	job := &Job{
+		MaxRetries:  2,
+		RetryPolicy: RetryPolicy{InitialInterval: time.Second, MaxInterval: time.Minute},
+		Attempts: []Attempt{
+			{Status: StatusInterrupted},
+			{Status: StatusFailed},
+			{Status: StatusInterrupted},
+		},
+	}
+	now := time.Now()
+
+	if _, ok := NextRetry(job, errors.New("boom"), now); !ok {
+		t.Fatal("interrupted attempts used up the job's retries")
+	}
+
+	job.Attempts = append(job.Attempts, Attempt{Status: StatusFailed})
+	if _, ok := NextRetry(job, errors.New("boom"), now); ok {
+		t.Error("job retried after MaxRetries failed attempts")
+	}
+}
+
+func TestBackoffStrategies(t *testing.T) {
+	noJitter := func(p RetryPolicy) RetryPolicy {
+		p.Jitter = JitterNone
+		return p
+	}
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		want   []time.Duration // delays before retries 1, 2, 3, ...
+	}{
+		{"fixed", noJitter(RetryPolicy{Strategy: BackoffFixed, InitialInterval: 5 * time.Second}),
+			[]time.Duration{5 * time.Second, 5 * time.Second, 5 * time.Second}},
+		{"linear", noJitter(RetryPolicy{Strategy: BackoffLinear, InitialInterval: time.Second}),
+			[]time.Duration{time.Second, 2 * time.Second, 3 * time.Second}},
+		{"exponential x3 capped", noJitter(RetryPolicy{Strategy: BackoffExponential, Multiplier: 3, InitialInterval: time.Second, MaxInterval: 20 * time.Second}),
+			[]time.Duration{time.Second, 3 * time.Second, 9 * time.Second, 20 * time.Second}},
+		{"schedule", noJitter(RetryPolicy{Strategy: BackoffSchedule, Schedule: []time.Duration{time.Second, time.Minute}}),
+			[]time.Duration{time.Second, time.Minute, time.Minute}},
+	}
+	for _, tt := range tests {
+		for i, want := range tt.want {
+			if got := tt.policy.Delay(i+1, nil); got != want {
+				t.Errorf("%s: retry %d delay = %v, want %v", tt.name, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestBackoffJitterIsSeeded(t *testing.T) {
+	for _, p := range []RetryPolicy{
+		{InitialInterval: time.Second, Jitter: JitterFull},
+		{InitialInterval: time.Second, Jitter: JitterEqual},
+		{InitialInterval: time.Second, MaxInterval: time.Minute, Strategy: BackoffDecorrelated},
+	} {
+		a := p.Delay(4, rand.New(rand.NewSource(42)))
+		b := p.Delay(4, rand.New(rand.NewSource(42)))
+		if a != b {
+			t.Errorf("%+v: same seed gave %v and %v", p, a, b)
+		}
+
+		low, high := time.Duration(0), 8*time.Second
+		switch {
+		case p.Jitter == JitterEqual:
+			low = 4 * time.Second
+		case p.Strategy == BackoffDecorrelated:
+			low, high = time.Second, time.Minute
+		}
+		if a < low || a > high {
+			t.Errorf("%+v: delay %v outside [%v, %v]", p, a, low, high)
+		}
+	}
+}
+
+func TestRegisterBackoff(t *testing.T) {
+	RegisterBackoff("test-square", func(attempt int, p RetryPolicy, _ *rand.Rand) time.Duration {
+		return p.InitialInterval * time.Duration(attempt*attempt)
+	})
+	p := RetryPolicy{Strategy: "test-square", InitialInterval: time.Second, Jitter: JitterNone}
+	if err := p.Validate(); err != nil {
+		t.Errorf("registered strategy rejected: %v", err)
+	}
+	if err := (RetryPolicy{Strategy: "test-cube"}).Validate(); err == nil {
+		t.Error("unknown strategy accepted")
+	}
+	if got := p.Delay(3, nil); got != 9*time.Second {
+		t.Errorf("custom strategy delay = %v, want 9s", got)
+	}
+
+	var decoded RetryPolicy
+	b, _ := json.Marshal(p)
+	if err := json.Unmarshal(b, &decoded); err != nil || decoded.Strategy != "test-square" || decoded.Jitter != JitterNone {
+		t.Errorf("policy did not round-trip through JSON: %s", b)
+	}
+}
@@ -1,27 +1,216 @@
 package core
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
 
+// Executor runs a job. The returned result, if any, must be JSON and is
+// stored on the job when it succeeds.
 type Executor interface {
-	Execute(ctx context.Context, job *Job) error
+	Execute(ctx context.Context, job *Job) (json.RawMessage, error)
 }
 
+// DefaultPollInterval is how long an idle worker waits between claims.
+const DefaultPollInterval = 2 * time.Second
+
+// DefaultStarvationPolls is how many claims in a row may pass over a job too
+// heavy for the free slots before the worker makes room for it.
+const DefaultStarvationPolls = 5
+
+// Worker runs jobs concurrently as long as their weights fit in its
+// capacity. Fetch claims the next job weighing at most maxWeight, or returns
+// nil when none is ready; blocked is the weight of a higher-ranked job it
+// passed over for not fitting, or 0. Process runs a claimed job to
+// completion.
 type Worker struct {
-	ID       string
-	Executor Executor
-	Sem      chan struct{}
-	StopCh   chan struct{}
+	ID           string
+	StopCh       chan struct{}
+	PollInterval time.Duration
+
+	// StarvationPolls is how many claims may pass over the same too-heavy
+	// job before the worker stops claiming lighter jobs until it has the
+	// slots for it. Otherwise a steady flow of light jobs could fill every
+	// slot as it frees up and keep a heavy one waiting forever.
+	StarvationPolls int
+
+	Fetch   func(ctx context.Context, w *Worker, maxWeight int) (job *Job, blocked int, err error)
+	Process func(ctx context.Context, w *Worker, job *Job)
+
+	capacity int
+
+	// Only touched by Run: how many claims in a row passed over a heavier
+	// job, and the weight being made room for once that reaches
+	// StarvationPolls.
+	passedOver int
+	reserve    int
+
+	mu     sync.Mutex
+	used   int
+	active map[string]int // job ID -> weight
+	freed  chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
 }
 
-func NewWorker(id string, executor Executor, maxConcurrency int) *Worker {
+func NewWorker(id string, capacity int) *Worker {
+	if capacity < 1 {
+		capacity = 1
+	}
 	return &Worker{
-		ID:       id,
-		Executor: executor,
-		Sem:      make(chan struct{}, maxConcurrency),
-		StopCh:   make(chan struct{}),
+		ID:              id,
+		StopCh:          make(chan struct{}),
+		PollInterval:    DefaultPollInterval,
+		StarvationPolls: DefaultStarvationPolls,
+		capacity:        capacity,
+		active:          make(map[string]int),
+		freed:           make(chan struct{}, 1),
+	}
+}
+
+// Capacity is the total weight of the jobs the worker runs at once.
+func (w *Worker) Capacity() int {
+	return w.capacity
+}
+
+// Used is the total weight of the jobs running on the worker.
+func (w *Worker) Used() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.used
+}
+
+// ActiveJobs returns the IDs of the jobs running on the worker, sorted.
+func (w *Worker) ActiveJobs() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ids := make([]string, 0, len(w.active))
+	for id := range w.active {
+		ids = append(ids, id)
 	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Stop tells Run to stop claiming jobs. Run returns once the jobs already
+// running have finished.
+func (w *Worker) Stop() {
+	w.once.Do(func() { close(w.StopCh) })
 }
 
+// Run claims and processes jobs until ctx is done or Stop is called. A job
+// is only claimed if its weight fits in the capacity left over by the jobs
+// already running.
 func (w *Worker) Run(ctx context.Context) {
-	// Worker run loop implementation
+	defer w.wg.Wait()
+
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if !w.waitForCapacity(ctx, max(w.reserve, 1)) {
+			return
+		}
+
+		job, ok := w.claim(ctx, ticker.C)
+		if !ok {
+			return
+		}
+		if job == nil {
+			// Making room for a heavy job.
+			continue
+		}
+		weight := job.SlotWeight()
+
+		w.mu.Lock()
+		w.used += weight
+		w.active[job.ID] = weight
+		w.mu.Unlock()
+
+		w.wg.Add(1)
+		go func() {
+			defer func() {
+				w.mu.Lock()
+				w.used -= weight
+				delete(w.active, job.ID)
+				w.mu.Unlock()
+				select {
+				case w.freed <- struct{}{}:
+				default:
+				}
+				w.wg.Done()
+			}()
+			w.Process(ctx, w, job)
+		}()
+	}
+}
+
+func (w *Worker) free() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.capacity - w.used
+}
+
+// waitForCapacity blocks until need slots are free, and reports false if
+// the worker was stopped first.
+func (w *Worker) waitForCapacity(ctx context.Context, need int) bool {
+	for w.free() < need {
+		select {
+		case <-w.freed:
+		case <-ctx.Done():
+			return false
+		case <-w.StopCh:
+			return false
+		}
+	}
+	return true
+}
+
+// claim polls Fetch until it returns a job, reporting false once the worker
+// is stopped. Only jobs that fit the capacity free at the time are asked
+// for; jobs finishing meanwhile widen the next poll. It returns no job when
+// the worker should stop claiming to make room for a heavy one.
+func (w *Worker) claim(ctx context.Context, poll <-chan time.Time) (*Job, bool) {
+	for {
+		job, blocked, err := w.Fetch(ctx, w, w.free())
+		if err != nil {
+			log.Printf("Worker %s dequeue error: %v\n", w.ID, err)
+		} else {
+			w.notePassedOver(blocked)
+			if job != nil {
+				return job, true
+			}
+			if w.reserve > w.free() {
+				return nil, true
+			}
+		}
+
+		select {
+		case <-poll:
+		case <-w.freed:
+			// More capacity: a heavier job may fit now.
+		case <-ctx.Done():
+			return nil, false
+		case <-w.StopCh:
+			return nil, false
+		}
+	}
+}
+
+// notePassedOver tracks how long a job of weight blocked has been passed
+// over, starting to reserve room for it after StarvationPolls claims.
+func (w *Worker) notePassedOver(blocked int) {
+	if blocked == 0 || blocked > w.capacity {
+		// Nothing waiting, or a job this worker can never run.
+		w.passedOver, w.reserve = 0, 0
+		return
+	}
+	w.passedOver++
+	if w.StarvationPolls > 0 && w.passedOver >= w.StarvationPolls {
+		w.reserve = blocked
+	}
 }
@@ -0,0 +1,202 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerRunRespectsCapacity(t *testing.T) {
+	const capacity, total = 3, 10
+
+	var mu sync.Mutex
+	next := 0
+	var running, peak, done atomic.Int32
+
+	w := NewWorker("w1", capacity)
+	w.PollInterval = time.Millisecond
+	w.Fetch = func(ctx context.Context, w *Worker, maxWeight int) (*Job, int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if next == total {
+			return nil, 0, nil
+		}
+		next++
+		return &Job{ID: fmt.Sprintf("job-%d", next)}, 0, nil
+	}
+	w.Process = func(ctx context.Context, w *Worker, job *Job) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		done.Add(1)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		w.Run(context.Background())
+		close(finished)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for done.Load() < total && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	w.Stop()
+	<-finished
+
+	if got := done.Load(); got != total {
+		t.Fatalf("processed %d jobs, want %d", got, total)
+	}
+	if got := peak.Load(); got > capacity {
+		t.Errorf("ran %d jobs at once, capacity is %d", got, capacity)
+	}
+	if got := peak.Load(); got < 2 {
+		t.Errorf("never ran jobs concurrently (peak %d)", got)
+	}
+	if active := w.ActiveJobs(); len(active) != 0 {
+		t.Errorf("active jobs after Run returned: %v", active)
+	}
+}
+
+func TestWorkerRunFitsJobWeights(t *testing.T) {
+	const capacity = 8
+	weights := []int{4, 1, 4, 1, 1, 4, 2, 1, 8, 1}
+
+	var mu sync.Mutex
+	next := 0
+	var used, peak, done atomic.Int32
+
+	w := NewWorker("w1", capacity)
+	w.PollInterval = time.Millisecond
+	w.Fetch = func(ctx context.Context, w *Worker, maxWeight int) (*Job, int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if next == len(weights) {
+			return nil, 0, nil
+		}
+		if weights[next] > maxWeight {
+			return nil, weights[next], nil
+		}
+		next++
+		return &Job{ID: fmt.Sprintf("job-%d", next), Weight: weights[next-1]}, 0, nil
+	}
+	w.Process = func(ctx context.Context, w *Worker, job *Job) {
+		n := used.Add(int32(job.Weight))
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		used.Add(-int32(job.Weight))
+		done.Add(1)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		w.Run(context.Background())
+		close(finished)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for int(done.Load()) < len(weights) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	w.Stop()
+	<-finished
+
+	if got := int(done.Load()); got != len(weights) {
+		t.Fatalf("processed %d jobs, want %d", got, len(weights))
+	}
+	if got := peak.Load(); got > capacity {
+		t.Errorf("ran weight %d at once, capacity is %d", got, capacity)
+	}
+	if got := w.Used(); got != 0 {
+		t.Errorf("used %d slots after Run returned", got)
+	}
+}
+
+func TestWorkerMakesRoomForHeavyJob(t *testing.T) {
+	const capacity = 4
+
+	// A heavy job reaches the head of the queue once light jobs fill the
+	// worker, and more light jobs keep coming. They finish one at a time, so
+	// left alone they would refill each slot as it frees up.
+	var mu sync.Mutex
+	heavyClaimed := false
+	light := 0
+	var used atomic.Int32
+	release := make(chan struct{})
+	heavyDone := make(chan int32, 1)
+
+	w := NewWorker("w1", capacity)
+	w.PollInterval = time.Millisecond
+	w.Fetch = func(ctx context.Context, w *Worker, maxWeight int) (*Job, int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		blocked := 0
+		if !heavyClaimed && light >= capacity {
+			if maxWeight >= capacity {
+				heavyClaimed = true
+				return &Job{ID: "heavy", Weight: capacity}, 0, nil
+			}
+			blocked = capacity
+		}
+		if maxWeight < 1 {
+			return nil, blocked, nil
+		}
+		light++
+		return &Job{ID: fmt.Sprintf("light-%d", light), Weight: 1}, blocked, nil
+	}
+	w.Process = func(ctx context.Context, w *Worker, job *Job) {
+		n := used.Add(int32(job.SlotWeight()))
+		if job.ID == "heavy" {
+			heavyDone <- n
+		} else {
+			<-release
+		}
+		used.Add(-int32(job.SlotWeight()))
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		w.Run(context.Background())
+		close(finished)
+	}()
+
+	timeout := time.After(5 * time.Second)
+wait:
+	for {
+		select {
+		case n := <-heavyDone:
+			if n > capacity {
+				t.Errorf("heavy job ran with %d slots in use, capacity is %d", n, capacity)
+			}
+			break wait
+		case release <- struct{}{}:
+			time.Sleep(2 * time.Millisecond)
+		case <-timeout:
+			t.Error("heavy job never claimed")
+			break wait
+		}
+	}
+
+	w.Stop()
+	for {
+		select {
+		case release <- struct{}{}:
+		case <-finished:
+			return
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+// DefaultMaxResponseSize bounds how much of a webhook response is read.
+const DefaultMaxResponseSize = 1 << 20
+
+// HTTPConfig is the executor_config understood by HTTPExecutor.
+type HTTPConfig struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method,omitempty"` // defaults to POST
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is a text/template rendered with .Job and .Payload (the decoded
+	// payload); a "json" function is available. Without it the raw payload
+	// is sent.
+	Body string `json:"body,omitempty"`
+	// Timeout overrides the job's timeout for the request.
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// HTTPStatusError is returned for a non-2xx webhook response.
+type HTTPStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("webhook responded %d", e.StatusCode)
+	}
+	return fmt.Sprintf("webhook responded %d: %s", e.StatusCode, e.Body)
+}
+
+// HTTPExecutor delivers a job to a webhook. A 2xx response succeeds and its
+// body becomes the job result. 408, 429 and 5xx responses are retried,
+// honouring Retry-After; any other status fails the job permanently.
+type HTTPExecutor struct {
+	Client *http.Client
+	// Secret, when set, signs every request: X-Wida-Signature carries
+	// "sha256=" plus the hex HMAC-SHA256 of "<X-Wida-Timestamp>.<body>".
+	Secret []byte
+	// DefaultTimeout applies when neither the config nor the job has one.
+	DefaultTimeout  time.Duration
+	MaxResponseSize int64
+}
+
+func NewHTTPExecutor(secret []byte) *HTTPExecutor {
+	return &HTTPExecutor{
+		Client:          &http.Client{},
+		Secret:          secret,
+		DefaultTimeout:  30 * time.Second,
+		MaxResponseSize: DefaultMaxResponseSize,
+	}
+}
+
+func (e *HTTPExecutor) Execute(ctx context.Context, job *core.Job) (json.RawMessage, error) {
+	var cfg HTTPConfig
+	if len(job.ExecutorConfig) > 0 {
+		if err := json.Unmarshal(job.ExecutorConfig, &cfg); err != nil {
+			return nil, core.Permanent(fmt.Errorf("invalid http executor config: %w", err))
+		}
+	}
+	if cfg.URL == "" {
+		return nil, core.Permanent(errors.New("http executor config has no url"))
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodPost
+	}
+
+	body, err := renderBody(cfg.Body, job)
+	if err != nil {
+		return nil, core.Permanent(err)
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = job.Timeout
+	}
+	if timeout <= 0 {
+		timeout = e.DefaultTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, cfg.Method, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, core.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("X-Wida-Job-ID", job.ID)
+	req.Header.Set("X-Wida-Attempt", strconv.Itoa(len(job.Attempts)+1))
+	if len(e.Secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Wida-Timestamp", timestamp)
+		req.Header.Set("X-Wida-Signature", "sha256="+Sign(e.Secret, timestamp, body))
+	}
+
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	limit := e.MaxResponseSize
+	if limit <= 0 {
+		limit = DefaultMaxResponseSize
+	}
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return respBody, nil
+	}
+
+	statusErr := &HTTPStatusError{StatusCode: resp.StatusCode, Body: truncate(string(respBody), 512)}
+	switch {
+	case resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode >= 500:
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return nil, core.RetryAfter(d, statusErr)
+		}
+		return nil, statusErr
+	default:
+		return nil, core.Permanent(statusErr)
+	}
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>", which receivers
+// compare against the X-Wida-Signature header.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func renderBody(tmpl string, job *core.Job) ([]byte, error) {
+	if tmpl == "" {
+		return job.Payload, nil
+	}
+
+	t, err := template.New("body").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body template: %w", err)
+	}
+
+	var payload interface{}
+	if len(job.Payload) > 0 {
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("payload is not valid JSON: %w", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, map[string]interface{}{"Job": job, "Payload": payload}); err != nil {
+		return nil, fmt.Errorf("rendering body template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// parseRetryAfter understands both forms of Retry-After: delay-seconds and
+// an HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
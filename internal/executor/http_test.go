@@ -0,0 +1,81 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+func TestHTTPExecutor(t *testing.T) {
+	secret := []byte("s3cret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/ok":
+			want := "sha256=" + Sign(secret, r.Header.Get("X-Wida-Timestamp"), body)
+			if r.Header.Get("X-Wida-Signature") != want {
+				http.Error(w, "bad signature", http.StatusUnauthorized)
+				return
+			}
+			if r.Header.Get("X-Tenant") != "acme" {
+				http.Error(w, "missing header", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"echo":` + string(body) + `}`))
+		case "/busy":
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/invalid":
+			http.Error(w, "nope", http.StatusUnprocessableEntity)
+		}
+	}))
+	defer srv.Close()
+
+	exec := NewHTTPExecutor(secret)
+	run := func(path, body string) (json.RawMessage, error) {
+		cfg, _ := json.Marshal(HTTPConfig{
+			URL:     srv.URL + path,
+			Headers: map[string]string{"X-Tenant": "acme"},
+			Body:    body,
+		})
+		job := &core.Job{ID: "job-1", Payload: json.RawMessage(`{"n":1}`), ExecutorConfig: cfg}
+		return exec.Execute(context.Background(), job)
+	}
+
+	result, err := run("/ok", "")
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if string(result) != `{"echo":{"n":1}}` {
+		t.Errorf("Unexpected result %s", result)
+	}
+
+	result, err = run("/ok", `{"id":{{json .Job.ID}},"n":{{.Payload.n}}}`)
+	if err != nil {
+		t.Fatalf("Expected success with templated body, got %v", err)
+	}
+	if string(result) != `{"echo":{"id":"job-1","n":1}}` {
+		t.Errorf("Unexpected templated result %s", result)
+	}
+
+	_, err = run("/busy", "")
+	if d, ok := core.RetryAfterDelay(err); !ok || d != 60*time.Second {
+		t.Errorf("Expected a 60s retry-after error, got %v", err)
+	}
+	if core.IsPermanent(err) {
+		t.Error("Expected 503 to be retryable")
+	}
+
+	_, err = run("/invalid", "")
+	var statusErr *HTTPStatusError
+	if !core.IsPermanent(err) || !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected a permanent 422 error, got %v", err)
+	}
+}
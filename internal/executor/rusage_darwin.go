@@ -0,0 +1,8 @@
+package executor
+
+import "syscall"
+
+// maxRSSKB converts ru_maxrss, which Darwin reports in bytes.
+func maxRSSKB(ru *syscall.Rusage) int64 {
+	return int64(ru.Maxrss) / 1024
+}
@@ -0,0 +1,10 @@
+//go:build unix && !darwin
+
+package executor
+
+import "syscall"
+
+// maxRSSKB returns ru_maxrss, which is already in kilobytes here.
+func maxRSSKB(ru *syscall.Rusage) int64 {
+	return int64(ru.Maxrss)
+}
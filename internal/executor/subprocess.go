@@ -0,0 +1,262 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+// Command is a program SubprocessExecutor is allowed to run. Jobs refer to
+// commands by name and can never supply a binary of their own.
+type Command struct {
+	Path string   `json:"path"`
+	Args []string `json:"args,omitempty"`
+	Env  []string `json:"env,omitempty"` // KEY=VALUE pairs
+	Dir  string   `json:"dir,omitempty"`
+
+	// AllowJobArgs lets a job append arguments; AllowJobEnv lists the
+	// variables a job may set.
+	AllowJobArgs bool     `json:"allow_job_args,omitempty"`
+	AllowJobEnv  []string `json:"allow_job_env,omitempty"`
+
+	// SuccessExitCodes defaults to [0]. PermanentExitCodes fail the job
+	// without retrying; any other code is retried.
+	SuccessExitCodes   []int `json:"success_exit_codes,omitempty"`
+	PermanentExitCodes []int `json:"permanent_exit_codes,omitempty"`
+}
+
+// SubprocessConfig is the executor_config understood by SubprocessExecutor.
+type SubprocessConfig struct {
+	Command string            `json:"command"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	// Payload is "stdin" (the default) or "file", in which case the path of
+	// a temporary file holding the payload is passed in WIDA_PAYLOAD_FILE.
+	Payload string        `json:"payload,omitempty"`
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// SubprocessExecutor runs allow-listed commands. The process runs in its own
+// process group, which is killed as a whole on timeout or cancellation, and
+// its exit code and resource usage are recorded on the attempt.
+type SubprocessExecutor struct {
+	Commands map[string]Command
+	// WaitDelay bounds how long to wait for output after the process exits
+	// or is killed, in case a stray child still holds its pipes open.
+	WaitDelay time.Duration
+}
+
+func NewSubprocessExecutor(commands map[string]Command) *SubprocessExecutor {
+	return &SubprocessExecutor{
+		Commands:  commands,
+		WaitDelay: 5 * time.Second,
+	}
+}
+
+func (e *SubprocessExecutor) Execute(ctx context.Context, job *core.Job) (json.RawMessage, error) {
+	var cfg SubprocessConfig
+	if len(job.ExecutorConfig) > 0 {
+		if err := json.Unmarshal(job.ExecutorConfig, &cfg); err != nil {
+			return nil, core.Permanent(fmt.Errorf("invalid subprocess executor config: %w", err))
+		}
+	}
+	command, ok := e.Commands[cfg.Command]
+	if !ok {
+		return nil, core.Permanent(fmt.Errorf("command %q is not allow-listed", cfg.Command))
+	}
+
+	args := slices.Clone(command.Args)
+	if len(cfg.Args) > 0 {
+		if !command.AllowJobArgs {
+			return nil, core.Permanent(fmt.Errorf("command %q does not accept job arguments", cfg.Command))
+		}
+		args = append(args, cfg.Args...)
+	}
+
+	// Processes start from a minimal environment rather than inheriting
+	// widad's, which holds the database credentials.
+	env := []string{"PATH=" + os.Getenv("PATH"), "HOME=" + os.Getenv("HOME")}
+	env = append(env, command.Env...)
+	for k, v := range cfg.Env {
+		if !slices.Contains(command.AllowJobEnv, k) {
+			return nil, core.Permanent(fmt.Errorf("command %q does not allow setting %s", cfg.Command, k))
+		}
+		env = append(env, k+"="+v)
+	}
+	env = append(env, "WIDA_JOB_ID="+job.ID, "WIDA_ATTEMPT="+strconv.Itoa(len(job.Attempts)+1))
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = job.Timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, command.Path, args...)
+	cmd.Dir = command.Dir
+	cmd.WaitDelay = e.WaitDelay
+	configureProcessGroup(cmd)
+
+	switch cfg.Payload {
+	case "", "stdin":
+		cmd.Stdin = bytes.NewReader(job.Payload)
+	case "file":
+		f, err := os.CreateTemp("", "wida-payload-*.json")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(f.Name())
+		_, err = f.Write(job.Payload)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, "WIDA_PAYLOAD_FILE="+f.Name())
+	default:
+		return nil, core.Permanent(fmt.Errorf("unknown payload mode %q", cfg.Payload))
+	}
+	cmd.Env = env
+
+	// Output goes to the job's log when there is one.
+	logLine := func(stream, line string) {
+		log.Printf("Job %s %s: %s\n", job.ID, stream, line)
+	}
+	if jc := core.JobContextFromContext(ctx); jc != nil {
+		logger := jc.Logger()
+		logLine = func(stream, line string) {
+			logger.Info(line, "stream", stream)
+		}
+	}
+
+	stderrTail := &tailBuffer{max: 2048}
+	stdout := newLineWriter(func(line string) {
+		logLine("stdout", line)
+	})
+	stderr := newLineWriter(func(line string) {
+		logLine("stderr", line)
+		stderrTail.WriteLine(line)
+	})
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	runErr := cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+
+	if cmd.ProcessState != nil {
+		if attempt := core.AttemptFromContext(ctx); attempt != nil {
+			code := cmd.ProcessState.ExitCode()
+			attempt.ExitCode = &code
+			attempt.Usage = resourceUsage(cmd.ProcessState)
+		}
+	}
+
+	if ctx.Err() != nil {
+		// Killed on timeout or cancellation.
+		return nil, fmt.Errorf("%s killed: %w", cfg.Command, context.Cause(ctx))
+	}
+
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) {
+		// The process could not be started at all.
+		return nil, core.Permanent(runErr)
+	}
+
+	code := cmd.ProcessState.ExitCode()
+	successCodes := command.SuccessExitCodes
+	if len(successCodes) == 0 {
+		successCodes = []int{0}
+	}
+	if slices.Contains(successCodes, code) {
+		return nil, nil
+	}
+
+	err := fmt.Errorf("%s exited with code %d", cfg.Command, code)
+	if tail := stderrTail.String(); tail != "" {
+		err = fmt.Errorf("%w: %s", err, tail)
+	}
+	if slices.Contains(command.PermanentExitCodes, code) {
+		return nil, core.Permanent(err)
+	}
+	return nil, err
+}
+
+// lineWriter calls fn for each complete line written to it.
+type lineWriter struct {
+	mu  sync.Mutex
+	buf []byte
+	fn  func(string)
+}
+
+func newLineWriter(fn func(string)) *lineWriter {
+	return &lineWriter{fn: fn}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.fn(string(bytes.TrimRight(w.buf[:i], "\r")))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush emits a trailing line that had no newline.
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.fn(string(w.buf))
+		w.buf = nil
+	}
+}
+
+// tailBuffer keeps roughly the last max bytes of the lines written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) WriteLine(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.buf) > 0 {
+		t.buf = append(t.buf, '\n')
+	}
+	t.buf = append(t.buf, line...)
+	if len(t.buf) > t.max {
+		t.buf = t.buf[len(t.buf)-t.max:]
+	}
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
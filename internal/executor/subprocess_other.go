@@ -0,0 +1,21 @@
+//go:build !unix
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+// configureProcessGroup is a no-op where process groups are unavailable;
+// cancellation kills only the direct child.
+func configureProcessGroup(cmd *exec.Cmd) {}
+
+func resourceUsage(state *os.ProcessState) *core.ResourceUsage {
+	return &core.ResourceUsage{
+		UserTime:   state.UserTime(),
+		SystemTime: state.SystemTime(),
+	}
+}
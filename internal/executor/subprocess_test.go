@@ -0,0 +1,60 @@
+//go:build unix
+
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+func TestSubprocessExecutor(t *testing.T) {
+	exec := NewSubprocessExecutor(map[string]Command{
+		"check": {Path: "/bin/sh", Args: []string{"-c", `grep -q '"ok":true' || { echo "bad payload" >&2; exit 3; }`}},
+		"file":  {Path: "/bin/sh", Args: []string{"-c", `grep -q '"ok":true' "$WIDA_PAYLOAD_FILE"`}},
+		"hang":  {Path: "/bin/sh", Args: []string{"-c", "sleep 30 & sleep 30"}},
+	})
+	run := func(command, payload, mode string, timeout time.Duration) (*core.Attempt, error) {
+		cfg, _ := json.Marshal(SubprocessConfig{Command: command, Payload: mode, Timeout: timeout})
+		job := &core.Job{ID: "job-1", Payload: json.RawMessage(payload), ExecutorConfig: cfg}
+		attempt := &core.Attempt{}
+		_, err := exec.Execute(core.WithAttempt(context.Background(), attempt), job)
+		return attempt, err
+	}
+
+	attempt, err := run("check", `{"ok":true}`, "", 0)
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if attempt.ExitCode == nil || *attempt.ExitCode != 0 || attempt.Usage == nil {
+		t.Errorf("Expected exit code 0 and usage on the attempt, got %+v", attempt)
+	}
+
+	if _, err := run("file", `{"ok":true}`, "file", 0); err != nil {
+		t.Errorf("Expected payload file to be readable, got %v", err)
+	}
+
+	attempt, err = run("check", `{"ok":false}`, "", 0)
+	if err == nil || !strings.Contains(err.Error(), "bad payload") || core.IsPermanent(err) {
+		t.Errorf("Expected a retryable failure carrying stderr, got %v", err)
+	}
+	if attempt.ExitCode == nil || *attempt.ExitCode != 3 {
+		t.Errorf("Expected exit code 3, got %+v", attempt.ExitCode)
+	}
+
+	start := time.Now()
+	if _, err := run("hang", `{}`, "", 200*time.Millisecond); err == nil {
+		t.Error("Expected the hanging command to be killed")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the process group to be killed promptly, took %v", elapsed)
+	}
+
+	if _, err := run("rm", `{}`, "", 0); !core.IsPermanent(err) {
+		t.Errorf("Expected a permanent error for a command outside the allow-list, got %v", err)
+	}
+}
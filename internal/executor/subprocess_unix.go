@@ -0,0 +1,31 @@
+//go:build unix
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+// configureProcessGroup starts the command in a new process group and makes
+// cancellation kill the whole group, so children of the command die too.
+func configureProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+func resourceUsage(state *os.ProcessState) *core.ResourceUsage {
+	usage := &core.ResourceUsage{
+		UserTime:   state.UserTime(),
+		SystemTime: state.SystemTime(),
+	}
+	if ru, ok := state.SysUsage().(*syscall.Rusage); ok {
+		usage.MaxRSSKB = maxRSSKB(ru)
+	}
+	return usage
+}
@@ -2,26 +2,47 @@ package scheduler
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/theb0imanuu/wida/internal/core"
 	"github.com/theb0imanuu/wida/internal/store"
 )
 
+// DefaultTimeoutMargin is how long a job may stay running past its timeout
+// before the scheduler decides its worker is gone.
+const DefaultTimeoutMargin = time.Minute
+
+// DefaultJobLogRetention is how long job log lines are kept.
+const DefaultJobLogRetention = 7 * 24 * time.Hour
+
 type Scheduler struct {
 	pool     *pgxpool.Pool
 	store    store.Store
 	quit     chan struct{}
 	isLeader atomic.Bool
+
+	// TimeoutMargin is added to Job.Timeout before a running job is
+	// considered abandoned and failed by the scheduler.
+	TimeoutMargin time.Duration
+
+	// JobLogRetention is how long job log lines are kept. Zero keeps them
+	// forever.
+	JobLogRetention time.Duration
 }
 
 func NewScheduler(pool *pgxpool.Pool, s store.Store) *Scheduler {
 	return &Scheduler{
-		pool:  pool,
-		store: s,
-		quit:  make(chan struct{}),
+		pool:          pool,
+		store:         s,
+		quit:          make(chan struct{}),
+		TimeoutMargin: DefaultTimeoutMargin,
+
+		JobLogRetention: DefaultJobLogRetention,
 	}
 }
 
@@ -92,8 +113,22 @@ func (s *Scheduler) runEvaluationLoop(ctx context.Context, lockID int64) {
 			// 1. Evaluate CRON schedules
 			s.evaluateCRON(ctx)
 
-			// 2. Evaluate DAGs (advance dependent jobs if dependencies succeeded)
+			// 2. Evaluate DAGs (advance dependent jobs if dependencies succeeded,
+			// fail them if one never will)
 			s.evaluateDAGs(ctx)
+			s.failDeadDependents(ctx)
+
+			// 3. Drop job results whose TTL has passed
+			s.purgeExpiredResults(ctx)
+
+			// 4. Fail jobs whose worker never reported back after the timeout
+			s.sweepTimedOut(ctx)
+
+			// 5. Delete finished jobs past their queue's retention
+			s.purgeRetainedJobs(ctx)
+
+			// 6. Delete job log lines past their retention
+			s.purgeJobLogs(ctx)
 		}
 	}
 }
@@ -107,7 +142,10 @@ func (s *Scheduler) evaluateCRON(ctx context.Context) {
 func (s *Scheduler) evaluateDAGs(ctx context.Context) {
 	// Query jobs that are pending and have dependencies.
 	// If all dependencies are 'success', clear the dependencies array
-	// so the worker pool can pick them up.
+	// so the worker pool can pick them up. A dependency that died has
+	// left wida_jobs for the DLQ, so it is looked for there too: without
+	// this a missing dependency counted as done. failDeadDependents fails
+	// the jobs it holds back.
 
 	query := `
 		UPDATE wida_jobs w1
@@ -121,6 +159,10 @@ func (s *Scheduler) evaluateDAGs(ctx context.Context) {
 		      JOIN wida_jobs w2 ON w2.id = dep_id
 		      WHERE w2.status != 'success'
 		  )
+		  AND NOT EXISTS (
+		      SELECT 1 FROM jsonb_array_elements_text(w1.dependencies) AS dep_id
+		      JOIN wida_dlq d ON d.id = dep_id
+		  )
 	`
 	res, err := s.pool.Exec(ctx, query)
 	if err != nil {
@@ -133,3 +175,147 @@ func (s *Scheduler) evaluateDAGs(ctx context.Context) {
 		log.Printf("Advanced %d DAG jobs to runnable state\n", rowsAffected)
 	}
 }
+
+// failDeadDependents moves pending jobs to the DLQ once one of their
+// dependencies died, was cancelled or was discarded, as they can never run.
+func (s *Scheduler) failDeadDependents(ctx context.Context) {
+	query := `
+		SELECT w1.id, dep_id, COALESCE(w2.status, 'dead')
+		FROM wida_jobs w1
+		CROSS JOIN LATERAL jsonb_array_elements_text(w1.dependencies) AS dep_id
+		LEFT JOIN wida_jobs w2 ON w2.id = dep_id
+		WHERE w1.status = 'pending'
+		  AND jsonb_typeof(w1.dependencies) = 'array'
+		  AND (w2.status IN ('dead', 'cancelled', 'discarded')
+		       OR (w2.id IS NULL AND EXISTS (SELECT 1 FROM wida_dlq d WHERE d.id = dep_id)))
+	`
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		log.Printf("DAG failure evaluation error: %v\n", err)
+		return
+	}
+	reasons := make(map[string]string)
+	for rows.Next() {
+		var jobID, depID string
+		var status core.Status
+		if err := rows.Scan(&jobID, &depID, &status); err != nil {
+			rows.Close()
+			log.Printf("DAG failure evaluation error: %v\n", err)
+			return
+		}
+		if reason := core.DependencyFailure(depID, status); reason != "" && reasons[jobID] == "" {
+			reasons[jobID] = reason
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Printf("DAG failure evaluation error: %v\n", err)
+		return
+	}
+
+	for jobID, reason := range reasons {
+		switch err := s.store.FailPending(ctx, jobID, reason); {
+		case errors.Is(err, store.ErrJobLost):
+			// It was cancelled or removed meanwhile.
+		case err != nil:
+			log.Printf("DAG failure error for job %s: %v\n", jobID, err)
+		default:
+			log.Printf("Job %s can never run, %s. Moved to DLQ.\n", jobID, reason)
+		}
+	}
+}
+
+func (s *Scheduler) purgeExpiredResults(ctx context.Context) {
+	query := `
+		UPDATE wida_jobs
+		SET result = NULL, result_expires_at = NULL
+		WHERE result_expires_at IS NOT NULL AND result_expires_at <= NOW()
+	`
+	res, err := s.pool.Exec(ctx, query)
+	if err != nil {
+		log.Printf("Result expiry error: %v\n", err)
+		return
+	}
+
+	if rowsAffected := res.RowsAffected(); rowsAffected > 0 {
+		log.Printf("Purged %d expired job results\n", rowsAffected)
+	}
+}
+
+func (s *Scheduler) purgeJobLogs(ctx context.Context) {
+	if s.JobLogRetention <= 0 {
+		return
+	}
+	query := `DELETE FROM wida_job_logs WHERE logged_at < NOW() - make_interval(secs => $1 / 1e9)`
+	res, err := s.pool.Exec(ctx, query, int64(s.JobLogRetention))
+	if err != nil {
+		log.Printf("Job log retention error: %v\n", err)
+		return
+	}
+
+	if rowsAffected := res.RowsAffected(); rowsAffected > 0 {
+		log.Printf("Deleted %d job log lines older than %v\n", rowsAffected, s.JobLogRetention)
+	}
+}
+
+func (s *Scheduler) purgeRetainedJobs(ctx context.Context) {
+	query := `
+		DELETE FROM wida_jobs j
+		USING wida_queues q
+		WHERE j.queue = q.name AND q.retention > 0
+		  AND j.status IN ('success', 'cancelled', 'discarded')
+		  AND j.updated_at < NOW() - make_interval(secs => q.retention / 1e9)
+	`
+	res, err := s.pool.Exec(ctx, query)
+	if err != nil {
+		log.Printf("Job retention error: %v\n", err)
+		return
+	}
+
+	if rowsAffected := res.RowsAffected(); rowsAffected > 0 {
+		log.Printf("Deleted %d jobs past their queue's retention\n", rowsAffected)
+	}
+}
+
+// sweepTimedOut fails running jobs that are well past their timeout. Their
+// worker would have timed them out itself, so it has died or lost touch; the
+// job is retried or moved to the DLQ like any other timeout. The writes are
+// fenced on the worker seen here, so a job that finished or was claimed again
+// since it was listed is left alone.
+func (s *Scheduler) sweepTimedOut(ctx context.Context) {
+	jobs, err := s.store.ListOverdue(ctx, s.TimeoutMargin)
+	if err != nil {
+		log.Printf("Timeout sweep error: %v\n", err)
+		return
+	}
+
+	for _, job := range jobs {
+		now := time.Now()
+		timeoutErr := fmt.Errorf("%w: no result from worker %s within %v of the %v deadline", core.ErrTimeout, job.WorkerID, s.TimeoutMargin, job.Timeout)
+		attempt := core.Attempt{
+			StartedAt:  *job.StartedAt,
+			FinishedAt: now,
+			Status:     core.StatusTimeout,
+			Error:      timeoutErr.Error(),
+		}
+		job.Attempts = append(job.Attempts, attempt)
+
+		var err error
+		nextRun, ok := core.NextRetry(job, timeoutErr, now)
+		if ok {
+			err = s.store.Retry(ctx, job.ID, job.WorkerID, &attempt, nextRun)
+		} else {
+			err = s.store.MoveToDLQ(ctx, job.ID, job.WorkerID, &attempt, timeoutErr.Error())
+		}
+		switch {
+		case errors.Is(err, store.ErrJobLost):
+			// Its worker got there first.
+		case err != nil:
+			log.Printf("Timeout sweep error for job %s: %v\n", job.ID, err)
+		case ok:
+			log.Printf("Job %s overran its timeout on worker %s, retrying at %v\n", job.ID, job.WorkerID, nextRun)
+		default:
+			log.Printf("Job %s overran its timeout on worker %s. Moved to DLQ.\n", job.ID, job.WorkerID)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// jobFinishedChannel carries the ID of every job that reaches a terminal
+// status. It is raised by the wida_jobs_finished trigger in schema.sql.
+const jobFinishedChannel = "wida_job_finished"
+
+// jobUpdatedChannel carries the ID of every job whose status or progress
+// changes. It is raised by the wida_jobs_updated trigger in schema.sql.
+const jobUpdatedChannel = "wida_job_updated"
+
+// listener fans out notifications from one Postgres channel to subscribers
+// keyed by payload. It LISTENs on a dedicated connection, started on first
+// use and re-established if it drops.
+type listener struct {
+	pool    *pgxpool.Pool
+	channel string
+
+	start sync.Once
+	ready chan struct{}
+
+	mu   sync.Mutex
+	subs map[string]map[chan struct{}]struct{}
+}
+
+func newListener(pool *pgxpool.Pool, channel string) *listener {
+	return &listener{
+		pool:    pool,
+		channel: channel,
+		ready:   make(chan struct{}),
+		subs:    make(map[string]map[chan struct{}]struct{}),
+	}
+}
+
+// subscribe returns a channel that is signalled whenever a notification
+// with the given payload arrives. It only returns once the listener is
+// LISTENing, so callers can check current state afterwards without racing
+// the notification.
+func (l *listener) subscribe(ctx context.Context, key string) (<-chan struct{}, func(), error) {
+	l.start.Do(func() { go l.run() })
+
+	select {
+	case <-l.ready:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	ch := make(chan struct{}, 1)
+	l.mu.Lock()
+	if l.subs[key] == nil {
+		l.subs[key] = make(map[chan struct{}]struct{})
+	}
+	l.subs[key][ch] = struct{}{}
+	l.mu.Unlock()
+
+	unsubscribe := func() {
+		l.mu.Lock()
+		delete(l.subs[key], ch)
+		if len(l.subs[key]) == 0 {
+			delete(l.subs, key)
+		}
+		l.mu.Unlock()
+	}
+	return ch, unsubscribe, nil
+}
+
+func (l *listener) run() {
+	first := true
+	for {
+		err := l.listen(context.Background(), &first)
+		log.Printf("Listener on %s lost: %v. Reconnecting...\n", l.channel, err)
+		time.Sleep(time.Second)
+	}
+}
+
+func (l *listener) listen(ctx context.Context, first *bool) error {
+	pooled, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection stays in LISTEN mode, so it never goes back to the pool.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{l.channel}.Sanitize()); err != nil {
+		return err
+	}
+	if *first {
+		*first = false
+		close(l.ready)
+	} else {
+		// Anything sent while we were disconnected is lost, so have every
+		// subscriber re-check its state.
+		l.broadcast()
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		l.notify(n.Payload)
+	}
+}
+
+func (l *listener) notify(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for ch := range l.subs[key] {
+		signal(ch)
+	}
+}
+
+func (l *listener) broadcast() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, chans := range l.subs {
+		for ch := range chans {
+			signal(ch)
+		}
+	}
+}
+
+// signal wakes a subscriber without blocking; one pending wake-up is enough.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
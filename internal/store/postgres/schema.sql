@@ -38,3 +38,144 @@ CREATE TABLE IF NOT EXISTS wida_workers (
     jobs_completed INTEGER NOT NULL DEFAULT 0,
     last_heartbeat TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
+
+-- Concurrency keys
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS concurrency_key VARCHAR(256);
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS concurrency_limit INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_wida_jobs_concurrency_key ON wida_jobs(concurrency_key, status) WHERE concurrency_key IS NOT NULL;
+
+-- Ordered job groups
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS group_key VARCHAR(256);
+
+CREATE INDEX IF NOT EXISTS idx_wida_jobs_group_key ON wida_jobs(group_key, created_at) WHERE group_key IS NOT NULL;
+
+-- Cancellation
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS cancel_requested BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Job results
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS result JSONB;
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS result_ttl BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS result_expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_wida_jobs_result_expires_at ON wida_jobs(result_expires_at) WHERE result_expires_at IS NOT NULL;
+
+-- Completion notifications, used to wake callers waiting on a job
+CREATE OR REPLACE FUNCTION wida_notify_job_finished() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('wida_job_finished', OLD.id);
+    ELSE
+        PERFORM pg_notify('wida_job_finished', NEW.id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER wida_jobs_finished
+    AFTER UPDATE OF status ON wida_jobs
+    FOR EACH ROW
+    WHEN (NEW.status IN ('success', 'dead', 'cancelled', 'discarded') AND OLD.status IS DISTINCT FROM NEW.status)
+    EXECUTE FUNCTION wida_notify_job_finished();
+
+-- Jobs leave wida_jobs when they move to the DLQ
+CREATE OR REPLACE TRIGGER wida_jobs_removed
+    AFTER DELETE ON wida_jobs
+    FOR EACH ROW
+    EXECUTE FUNCTION wida_notify_job_finished();
+
+-- Executor routing
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS executor VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS executor_config JSONB;
+
+-- Job types, used by the typed handler registry
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS job_type VARCHAR(128);
+
+-- Timeout enforcement
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS started_at TIMESTAMP WITH TIME ZONE;
+
+-- Worker capacity
+ALTER TABLE wida_workers ADD COLUMN IF NOT EXISTS capacity INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE wida_workers ADD COLUMN IF NOT EXISTS active_jobs INTEGER NOT NULL DEFAULT 0;
+
+-- Job priority: higher runs first
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS priority INTEGER NOT NULL DEFAULT 0;
+
+-- Queue definitions: defaults applied to jobs at enqueue
+CREATE TABLE IF NOT EXISTS wida_queues (
+    name VARCHAR(128) PRIMARY KEY,
+    retry_policy JSONB,
+    max_retries INTEGER NOT NULL DEFAULT 0,
+    timeout BIGINT NOT NULL DEFAULT 0,
+    priority INTEGER NOT NULL DEFAULT 0,
+    executor VARCHAR(64),
+    retention BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Worker labels and job placement constraints
+ALTER TABLE wida_workers ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}'::jsonb;
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS required_labels JSONB;
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS preferred_labels JSONB;
+
+-- Job weights: worker slots a job occupies while it runs
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS weight INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE wida_workers ADD COLUMN IF NOT EXISTS used_slots INTEGER NOT NULL DEFAULT 0;
+
+-- Events, such as circuit breaker state changes
+CREATE TABLE IF NOT EXISTS wida_events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    node VARCHAR(128),
+    data JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_wida_events_type ON wida_events(type, id);
+
+-- Job progress and checkpoints, reported by running jobs
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS progress JSONB;
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS checkpoint BYTEA;
+
+CREATE OR REPLACE FUNCTION wida_notify_job_updated() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('wida_job_updated', OLD.id);
+    ELSE
+        PERFORM pg_notify('wida_job_updated', NEW.id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER wida_jobs_updated
+    AFTER UPDATE OF status, progress ON wida_jobs
+    FOR EACH ROW
+    WHEN (OLD.status IS DISTINCT FROM NEW.status OR OLD.progress IS DISTINCT FROM NEW.progress)
+    EXECUTE FUNCTION wida_notify_job_updated();
+
+CREATE OR REPLACE TRIGGER wida_jobs_updated_removed
+    AFTER DELETE ON wida_jobs
+    FOR EACH ROW
+    EXECUTE FUNCTION wida_notify_job_updated();
+
+-- Job logs, written by running jobs through their job context. They outlive
+-- the job so the logs of jobs moved to the DLQ can still be read.
+CREATE TABLE IF NOT EXISTS wida_job_logs (
+    id BIGSERIAL PRIMARY KEY,
+    job_id VARCHAR(128) NOT NULL,
+    attempt INTEGER NOT NULL,
+    level VARCHAR(16) NOT NULL,
+    message TEXT NOT NULL,
+    fields JSONB,
+    logged_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_wida_job_logs_job_id ON wida_job_logs(job_id, id);
+CREATE INDEX IF NOT EXISTS idx_wida_job_logs_logged_at ON wida_job_logs(logged_at);
+
+-- Child jobs, spawned by a running job and enqueued when it completes. There
+-- is no foreign key, so children outlive a parent deleted by retention.
+ALTER TABLE wida_jobs ADD COLUMN IF NOT EXISTS parent_id VARCHAR(128);
+CREATE INDEX IF NOT EXISTS idx_wida_jobs_parent_id ON wida_jobs(parent_id) WHERE parent_id IS NOT NULL;
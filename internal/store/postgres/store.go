@@ -7,21 +7,60 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/theb0imanuu/wida/internal/core"
+	"github.com/theb0imanuu/wida/internal/store"
 )
 
 type Store struct {
-	pool *pgxpool.Pool
+	pool     *pgxpool.Pool
+	finished *listener
+	updated  *listener
 }
 
 func NewStore(pool *pgxpool.Pool) *Store {
 	return &Store{
-		pool: pool,
+		pool:     pool,
+		finished: newListener(pool, jobFinishedChannel),
+		updated:  newListener(pool, jobUpdatedChannel),
 	}
 }
 
 func (s *Store) Enqueue(ctx context.Context, job *core.Job) error {
+	if err := s.prepareJob(ctx, job); err != nil {
+		return err
+	}
+	return insertJob(ctx, s.pool, job)
+}
+
+// execer is the part of a pool or transaction that insertJob needs.
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// prepareJob fills in the defaults a job is stored with.
+func (s *Store) prepareJob(ctx context.Context, job *core.Job) error {
+	if err := job.ResolveConcurrencyKey(); err != nil {
+		return err
+	}
+	queue, err := s.GetQueue(ctx, job.Queue)
+	if err != nil {
+		return err
+	}
+	if queue != nil {
+		queue.ApplyDefaults(job)
+	}
+	if job.Executor == "" {
+		job.Executor = core.DefaultExecutor
+	}
+	if job.MaxRetries < 0 {
+		job.MaxRetries = 0
+	}
+	return nil
+}
+
+func insertJob(ctx context.Context, db execer, job *core.Job) error {
 	payloadBytes, err := json.Marshal(job.Payload)
 	if err != nil {
 		return err
@@ -30,114 +69,366 @@ func (s *Store) Enqueue(ctx context.Context, job *core.Job) error {
 	depsBytes, _ := json.Marshal(job.Dependencies)
 	depsOutBytes, _ := json.Marshal(job.Dependents)
 
+	// created_at is the clock time rather than the transaction's NOW(), so
+	// that jobs inserted together, like the children of one Complete, keep
+	// their order within a group.
 	query := `
 		INSERT INTO wida_jobs 
-		(id, queue, payload, status, run_at, cron_expr, retry_policy, timeout, max_retries, dependencies, dependents)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		(id, queue, payload, status, run_at, cron_expr, retry_policy, timeout, max_retries, dependencies, dependents,
+		 concurrency_key, concurrency_limit, group_key, result_ttl, executor, executor_config, job_type, priority,
+		 required_labels, preferred_labels, weight, parent_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23,
+		        clock_timestamp())
 	`
-	_, err = s.pool.Exec(ctx, query,
+	_, err = db.Exec(ctx, query,
 		job.ID, job.Queue, payloadBytes, job.Status,
 		job.RunAt, job.CronExpr, retryBytes, int64(job.Timeout),
 		job.MaxRetries, depsBytes, depsOutBytes,
+		nullString(job.ConcurrencyKey), job.ConcurrencyLimit, nullString(job.GroupKey),
+		int64(job.ResultTTL), job.Executor, nullJSON(job.ExecutorConfig), nullString(job.Type),
+		job.Priority, labelsJSON(job.RequiredLabels), labelsJSON(job.PreferredLabels),
+		job.SlotWeight(), nullString(job.ParentID),
 	)
 	return err
 }
 
-func (s *Store) Dequeue(ctx context.Context, queues []string, workerID string) (*core.Job, error) {
-	// Simple dequeue: find a pending job or a job where run_at <= now()
-	// SKIP LOCKED is critical for performance and removing deadlocks
-	query := `
-		UPDATE wida_jobs
-		SET status = 'running', worker_id = $1, last_heartbeat = NOW()
-		WHERE id = (
-			SELECT id FROM wida_jobs
-			WHERE status = 'pending' AND queue = ANY($2)
-			  AND (run_at IS NULL OR run_at <= NOW())
-			  AND (
-				dependencies IS NULL 
-				OR jsonb_typeof(dependencies) = 'null' 
-				OR (jsonb_typeof(dependencies) = 'array' AND jsonb_array_length(dependencies) = 0)
-			  )
-			ORDER BY run_at ASC NULLS FIRST, created_at ASC
-			FOR UPDATE SKIP LOCKED
-			LIMIT 1
-		)
-		RETURNING id, queue, payload, status, run_at, cron_expr, retry_policy, timeout, max_retries, attempts, dependencies, dependents
-	`
+// dequeueBatchSize bounds how many candidates a single Dequeue locks while
+// looking for one it is allowed to claim.
+const dequeueBatchSize = 16
 
-	row := s.pool.QueryRow(ctx, query, workerID, queues)
+// readyJobs selects the pending jobs a worker may claim, apart from their
+// weight, with $1 the queues, $2 the executors, $3 the worker's labels and
+// $4 the preference wait. A grouped job is only ready while no older job
+// of its group is still unfinished, which holds even when that job is
+// locked by another worker's claim. Jobs whose concurrency key is already
+// saturated are left out, so however many of them are queued they cannot
+// crowd claimable jobs out of a batch.
+const readyJobs = `
+	j.status = 'pending' AND j.queue = ANY($1) AND j.executor = ANY($2)
+	  AND (j.run_at IS NULL OR j.run_at <= NOW())
+	  AND (
+		j.dependencies IS NULL 
+		OR jsonb_typeof(j.dependencies) = 'null' 
+		OR (jsonb_typeof(j.dependencies) = 'array' AND jsonb_array_length(j.dependencies) = 0)
+	  )
+	  AND (j.group_key IS NULL OR NOT EXISTS (
+		SELECT 1 FROM wida_jobs g
+		WHERE g.group_key = j.group_key AND g.id <> j.id
+		  AND (
+			g.status = 'running'
+			OR (g.status IN ('pending', 'failed') AND (g.created_at, g.id) < (j.created_at, j.id))
+		  )
+	  ))
+	  AND (j.required_labels IS NULL OR $3::jsonb @> j.required_labels)
+	  AND (j.preferred_labels IS NULL OR $3::jsonb @> j.preferred_labels
+	       OR COALESCE(j.run_at, j.created_at) <= NOW() - make_interval(secs => $4 / 1e9))
+	  AND (j.concurrency_key IS NULL OR (
+		SELECT COUNT(*) FROM wida_jobs r
+		WHERE r.concurrency_key = j.concurrency_key AND r.status = 'running'
+	  ) < j.concurrency_limit)`
 
-	var job core.Job
-	var payloadBytes, retryBytes, attemptsBytes, depsBytes, depsOutBytes []byte
-	var timeoutInt int64
-	var cronExpr *string
-
-	err := row.Scan(
-		&job.ID, &job.Queue, &payloadBytes, &job.Status,
-		&job.RunAt, &cronExpr, &retryBytes, &timeoutInt,
-		&job.MaxRetries, &attemptsBytes, &depsBytes, &depsOutBytes,
-	)
+// readyJobOrder ranks ready jobs, $5 making the order of the queues in $1 a
+// precedence.
+const readyJobOrder = `
+	ORDER BY CASE WHEN $5 THEN array_position($1, j.queue::text) END,
+	         j.priority DESC, j.run_at ASC NULLS FIRST, j.created_at ASC`
 
+func (s *Store) Dequeue(ctx context.Context, opts store.DequeueOptions) (*core.Job, int, error) {
+	tx, err := s.pool.Begin(ctx)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, nil // No jobs available
+		return nil, 0, fmt.Errorf("failed to dequeue: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	labels, _ := json.Marshal(opts.Labels)
+
+	// With a weight limit, note whether the top-ranked ready job is too
+	// heavy, so the worker can make room for it if it keeps being passed
+	// over.
+	blocked := 0
+	if opts.MaxWeight > 0 {
+		var head int
+		err := tx.QueryRow(ctx, `SELECT j.weight FROM wida_jobs j WHERE `+readyJobs+readyJobOrder+` LIMIT 1`,
+			opts.Queues, opts.Executors, string(labels), int64(opts.PreferenceWait), opts.QueueOrder).Scan(&head)
+		if err != nil && err != pgx.ErrNoRows {
+			return nil, 0, fmt.Errorf("failed to dequeue: %w", err)
+		}
+		if head > opts.MaxWeight {
+			blocked = head
 		}
-		return nil, fmt.Errorf("failed to dequeue: %w", err)
 	}
 
-	job.Timeout = time.Duration(timeoutInt)
-	json.Unmarshal(payloadBytes, &job.Payload)
-	json.Unmarshal(retryBytes, &job.RetryPolicy)
-	if attemptsBytes != nil {
-		json.Unmarshal(attemptsBytes, &job.Attempts)
+	// SKIP LOCKED is critical for performance and removing deadlocks; the
+	// batch lets us pass over keys that another worker is claiming right now
+	// without another round trip.
+	query := `
+		SELECT j.id, j.concurrency_key, j.concurrency_limit FROM wida_jobs j
+		WHERE ` + readyJobs + `
+		  AND ($6 = 0 OR j.weight <= $6)
+		` + readyJobOrder + `
+		LIMIT $7
+		FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.Query(ctx, query, opts.Queues, opts.Executors, string(labels), int64(opts.PreferenceWait),
+		opts.QueueOrder, opts.MaxWeight, dequeueBatchSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to dequeue: %w", err)
 	}
-	if depsBytes != nil {
-		json.Unmarshal(depsBytes, &job.Dependencies)
+
+	type candidate struct {
+		id    string
+		key   *string
+		limit int
 	}
-	if depsOutBytes != nil {
-		json.Unmarshal(depsOutBytes, &job.Dependents)
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.id, &c.key, &c.limit); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("failed to dequeue: %w", err)
+		}
+		candidates = append(candidates, c)
 	}
-	if cronExpr != nil {
-		job.CronExpr = *cronExpr
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to dequeue: %w", err)
 	}
 
-	return &job, nil
+	for _, c := range candidates {
+		if c.key != nil {
+			ok, err := s.acquireConcurrencySlot(ctx, tx, *c.key, c.limit)
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to dequeue: %w", err)
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		row := tx.QueryRow(ctx, `
+			UPDATE wida_jobs
+			SET status = 'running', worker_id = $1, last_heartbeat = NOW(), started_at = NOW(), progress = NULL
+			WHERE id = $2
+			RETURNING `+jobColumns+`, checkpoint`, opts.WorkerID, c.id)
+		var checkpoint []byte
+		job, err := scanJob(row, &checkpoint)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to dequeue: %w", err)
+		}
+		job.Checkpoint = checkpoint
+		if err := tx.Commit(ctx); err != nil {
+			return nil, 0, fmt.Errorf("failed to dequeue: %w", err)
+		}
+		return job, blocked, nil
+	}
+
+	return nil, blocked, nil // No jobs available
 }
 
-func (s *Store) Heartbeat(ctx context.Context, jobID string, workerID string) error {
-	query := `UPDATE wida_jobs SET last_heartbeat = NOW() WHERE id = $1 AND worker_id = $2 AND status = 'running'`
-	_, err := s.pool.Exec(ctx, query, jobID, workerID)
-	return err
+// acquireConcurrencySlot reports whether another job with the given key may
+// start. A transaction-scoped advisory lock serialises claimers of the same
+// key, so the running count read afterwards already includes any claim that
+// committed before us. If another worker is claiming the key right now we
+// skip it rather than wait.
+func (s *Store) acquireConcurrencySlot(ctx context.Context, tx pgx.Tx, key string, limit int) (bool, error) {
+	var locked bool
+	err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock(hashtext('wida:concurrency:' || $1))`, key).Scan(&locked)
+	if err != nil || !locked {
+		return false, err
+	}
+
+	var running int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM wida_jobs WHERE concurrency_key = $1 AND status = 'running'`, key).Scan(&running)
+	if err != nil {
+		return false, err
+	}
+	return running < limit, nil
 }
 
-func (s *Store) UpdateStatus(ctx context.Context, jobID string, status core.Status, attempt *core.Attempt) error {
+// Heartbeat refreshes the job's liveness and reports whether it has been
+// cancelled in the meantime.
+func (s *Store) Heartbeat(ctx context.Context, jobID string, workerID string) (bool, error) {
+	query := `
+		UPDATE wida_jobs SET last_heartbeat = NOW()
+		WHERE id = $1 AND worker_id = $2 AND status = 'running'
+		RETURNING cancel_requested
+	`
+	var cancelRequested bool
+	err := s.pool.QueryRow(ctx, query, jobID, workerID).Scan(&cancelRequested)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	return cancelRequested, err
+}
+
+// UpdateStatus finishes a running job with status, appending the attempt.
+// Like Retry and Release, it clears the worker and any cancel request, so a
+// cancelled job looks the same whichever path finished it.
+func (s *Store) UpdateStatus(ctx context.Context, jobID, workerID string, status core.Status, attempt *core.Attempt) error {
 	query := `
 		UPDATE wida_jobs
-		SET status = $1, 
-		    attempts = COALESCE(attempts, '[]'::jsonb) || $2::jsonb
-		WHERE id = $3
+		SET status = $1, cancel_requested = FALSE, worker_id = NULL,
+		    attempts = COALESCE(attempts, '[]'::jsonb) || $2::jsonb,
+		    updated_at = NOW()
+		WHERE id = $3 AND worker_id = $4 AND status = 'running'
 	`
 	attemptBytes, _ := json.Marshal(attempt)
-	_, err := s.pool.Exec(ctx, query, status, string(attemptBytes), jobID)
-	return err
+	tag, err := s.pool.Exec(ctx, query, status, string(attemptBytes), jobID, workerID)
+	return lostIfNone(tag, err)
+}
+
+// Complete marks a job successful and stores its result, which expires after
+// the job's ResultTTL when one is set. The spawned jobs are enqueued in the
+// same transaction, and only if the job was still held by workerID.
+func (s *Store) Complete(ctx context.Context, job *core.Job, workerID string, attempt *core.Attempt, spawned []*core.Job) error {
+	for _, child := range spawned {
+		if err := s.prepareJob(ctx, child); err != nil {
+			return fmt.Errorf("spawned job %s: %w", child.ID, err)
+		}
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE wida_jobs
+		SET status = 'success',
+		    attempts = COALESCE(attempts, '[]'::jsonb) || $1::jsonb,
+		    result = $2,
+		    result_expires_at = CASE WHEN result_ttl > 0 AND $2::jsonb IS NOT NULL
+		                             THEN NOW() + make_interval(secs => result_ttl / 1e9) END,
+		    updated_at = NOW()
+		WHERE id = $3 AND worker_id = $4 AND status = 'running'
+	`
+	attemptBytes, _ := json.Marshal(attempt)
+	tag, err := tx.Exec(ctx, query, string(attemptBytes), nullJSON(job.Result), job.ID, workerID)
+	if err := lostIfNone(tag, err); err != nil {
+		return err
+	}
+	for _, child := range spawned {
+		if err := insertJob(ctx, tx, child); err != nil {
+			return fmt.Errorf("spawned job %s: %w", child.ID, err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// Retry makes the job pending again at runAt, unless it was cancelled while
+// running: then it ends as cancelled rather than run again before anyone
+// notices the cancellation.
+func (s *Store) Retry(ctx context.Context, jobID, workerID string, attempt *core.Attempt, runAt time.Time) error {
+	query := `
+		UPDATE wida_jobs
+		SET status = CASE WHEN cancel_requested THEN 'cancelled' ELSE 'pending' END,
+		    run_at = CASE WHEN cancel_requested THEN run_at ELSE $1 END,
+		    cancel_requested = FALSE, worker_id = NULL, progress = NULL,
+		    attempts = CASE WHEN $2::jsonb IS NULL THEN attempts
+		                    ELSE COALESCE(attempts, '[]'::jsonb) || $2::jsonb END,
+		    updated_at = NOW()
+		WHERE id = $3 AND worker_id = $4 AND status = 'running'
+	`
+	var attemptBytes []byte
+	if attempt != nil {
+		attemptBytes, _ = json.Marshal(attempt)
+	}
+	tag, err := s.pool.Exec(ctx, query, runAt, nullJSON(attemptBytes), jobID, workerID)
+	return lostIfNone(tag, err)
+}
+
+// Release, like Retry, ends a job cancelled while running as cancelled.
+func (s *Store) Release(ctx context.Context, jobID, workerID string, attempt *core.Attempt) error {
+	query := `
+		UPDATE wida_jobs
+		SET status = CASE WHEN cancel_requested THEN 'cancelled' ELSE 'pending' END,
+		    cancel_requested = FALSE, worker_id = NULL, progress = NULL,
+		    attempts = CASE WHEN $1::jsonb IS NULL THEN attempts
+		                    ELSE COALESCE(attempts, '[]'::jsonb) || $1::jsonb END,
+		    updated_at = NOW()
+		WHERE id = $2 AND worker_id = $3 AND status = 'running'
+	`
+	var attemptBytes []byte
+	if attempt != nil {
+		attemptBytes, _ = json.Marshal(attempt)
+	}
+	tag, err := s.pool.Exec(ctx, query, nullJSON(attemptBytes), jobID, workerID)
+	return lostIfNone(tag, err)
+}
+
+// FailPending moves a pending job that can never run, such as one whose
+// dependency died, to the DLQ. It returns ErrJobLost if the job is no
+// longer pending.
+func (s *Store) FailPending(ctx context.Context, jobID, reason string) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var queue string
+	var payloadBytes, attemptsBytes []byte
+	err = tx.QueryRow(ctx, `
+		DELETE FROM wida_jobs WHERE id = $1 AND status = 'pending'
+		RETURNING queue, payload, attempts
+	`, jobID).Scan(&queue, &payloadBytes, &attemptsBytes)
+	if err == pgx.ErrNoRows {
+		return store.ErrJobLost
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO wida_dlq (id, queue, payload, reason, attempts)
+		VALUES ($1, $2, $3, $4, $5)
+	`, jobID, queue, payloadBytes, reason, attemptsBytes)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func (s *Store) MoveToDLQ(ctx context.Context, jobID string, reason string) error {
+// MoveToDLQ appends the final attempt to a running job and moves it to the
+// DLQ, provided workerID still holds it. A job cancelled while running is
+// finished as cancelled instead.
+func (s *Store) MoveToDLQ(ctx context.Context, jobID, workerID string, attempt *core.Attempt, reason string) error {
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(ctx)
 
-	// Get job details
+	// Lock the job so it cannot finish some other way while it moves.
 	var queue string
 	var payloadBytes, attemptsBytes []byte
-	err = tx.QueryRow(ctx, `SELECT queue, payload, attempts FROM wida_jobs WHERE id = $1`, jobID).
-		Scan(&queue, &payloadBytes, &attemptsBytes)
+	var cancelRequested bool
+	attemptBytes, _ := json.Marshal(attempt)
+	err = tx.QueryRow(ctx, `
+		SELECT queue, payload, COALESCE(attempts, '[]'::jsonb) || $3::jsonb, cancel_requested
+		FROM wida_jobs WHERE id = $1 AND worker_id = $2 AND status = 'running'
+		FOR UPDATE
+	`, jobID, workerID, string(attemptBytes)).Scan(&queue, &payloadBytes, &attemptsBytes, &cancelRequested)
+	if err == pgx.ErrNoRows {
+		return store.ErrJobLost
+	}
 	if err != nil {
 		return err
 	}
 
+	if cancelRequested {
+		_, err = tx.Exec(ctx, `
+			UPDATE wida_jobs
+			SET status = 'cancelled', cancel_requested = FALSE, worker_id = NULL, attempts = $1, updated_at = NOW()
+			WHERE id = $2
+		`, attemptsBytes, jobID)
+		if err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	}
+
 	// Insert into DLQ
 	_, err = tx.Exec(ctx, `
 		INSERT INTO wida_dlq (id, queue, payload, reason, attempts)
@@ -156,58 +447,144 @@ func (s *Store) MoveToDLQ(ctx context.Context, jobID string, reason string) erro
 	return tx.Commit(ctx)
 }
 
-func (s *Store) GetJob(ctx context.Context, id string) (*core.Job, error) {
-	query := `
-		SELECT id, queue, payload, status, run_at, cron_expr, retry_policy, timeout, max_retries, attempts, dependencies, dependents
-		FROM wida_jobs
-		WHERE id = $1
-	`
-	row := s.pool.QueryRow(ctx, query, id)
+// lostIfNone turns a fenced update that matched no row into store.ErrJobLost.
+func lostIfNone(tag pgconn.CommandTag, err error) error {
+	if err == nil && tag.RowsAffected() == 0 {
+		return store.ErrJobLost
+	}
+	return err
+}
 
-	var job core.Job
-	var payloadBytes, retryBytes, attemptsBytes, depsBytes, depsOutBytes []byte
-	var timeoutInt int64
-	var cronExpr *string
+// Cancel moves a pending job straight to cancelled. A running job is only
+// flagged; its worker sees the flag on the next heartbeat and stops it.
+func (s *Store) Cancel(ctx context.Context, jobID string) (*core.Job, error) {
+	query := `
+		UPDATE wida_jobs
+		SET status = CASE WHEN status = 'running' THEN status ELSE 'cancelled' END,
+		    cancel_requested = (status = 'running'),
+		    updated_at = NOW()
+		WHERE id = $1 AND status IN ('pending', 'failed', 'running')
+		RETURNING ` + jobColumns
+	job, err := scanJob(s.pool.QueryRow(ctx, query, jobID))
+	if err == pgx.ErrNoRows {
+		job, err = s.GetJob(ctx, jobID)
+		if err != nil || job == nil {
+			return nil, err
+		}
+		return job, store.ErrNotCancellable
+	}
+	return job, err
+}
 
-	err := row.Scan(
-		&job.ID, &job.Queue, &payloadBytes, &job.Status,
-		&job.RunAt, &cronExpr, &retryBytes, &timeoutInt,
-		&job.MaxRetries, &attemptsBytes, &depsBytes, &depsOutBytes,
-	)
+// GetJob is the only read that loads the result, which can be large.
+func (s *Store) GetJob(ctx context.Context, id string) (*core.Job, error) {
+	query := `SELECT ` + jobColumns + `, result FROM wida_jobs WHERE id = $1`
+	var resultBytes []byte
+	job, err := scanJob(s.pool.QueryRow(ctx, query, id), &resultBytes)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil // Not found
 		}
 		return nil, err
 	}
+	if resultBytes != nil {
+		job.Result = resultBytes
+	}
+	return job, nil
+}
 
-	if cronExpr != nil {
-		job.CronExpr = *cronExpr
+func (s *Store) UpdateProgress(ctx context.Context, jobID, workerID string, progress core.Progress) error {
+	query := `
+		UPDATE wida_jobs SET progress = $1
+		WHERE id = $2 AND worker_id = $3 AND status = 'running'
+	`
+	progressBytes, _ := json.Marshal(progress)
+	_, err := s.pool.Exec(ctx, query, string(progressBytes), jobID, workerID)
+	return err
+}
+
+func (s *Store) SaveCheckpoint(ctx context.Context, jobID, workerID string, data []byte) error {
+	query := `
+		UPDATE wida_jobs SET checkpoint = $1
+		WHERE id = $2 AND worker_id = $3 AND status = 'running'
+	`
+	_, err := s.pool.Exec(ctx, query, data, jobID, workerID)
+	return err
+}
+
+func (s *Store) AppendJobLogs(ctx context.Context, logs []core.JobLog) error {
+	batch := &pgx.Batch{}
+	for _, l := range logs {
+		var fields []byte
+		if len(l.Fields) > 0 {
+			fields = marshalLogFields(l.Fields)
+		}
+		batch.Queue(`
+			INSERT INTO wida_job_logs (job_id, attempt, level, message, fields, logged_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, l.JobID, l.Attempt, l.Level, l.Message, nullJSON(fields), l.Time)
 	}
-	job.Timeout = time.Duration(timeoutInt)
-	json.Unmarshal(payloadBytes, &job.Payload)
-	json.Unmarshal(retryBytes, &job.RetryPolicy)
-	if attemptsBytes != nil {
-		json.Unmarshal(attemptsBytes, &job.Attempts)
+	return s.pool.SendBatch(ctx, batch).Close()
+}
+
+// marshalLogFields encodes a log line's fields, falling back to fmt.Sprint
+// for values JSON cannot hold, like channels or NaN.
+func marshalLogFields(fields map[string]any) []byte {
+	if b, err := json.Marshal(fields); err == nil {
+		return b
 	}
-	if depsBytes != nil {
-		json.Unmarshal(depsBytes, &job.Dependencies)
+	safe := make(map[string]any, len(fields))
+	for k, v := range fields {
+		if _, err := json.Marshal(v); err != nil {
+			v = fmt.Sprint(v)
+		}
+		safe[k] = v
 	}
-	if depsOutBytes != nil {
-		json.Unmarshal(depsOutBytes, &job.Dependents)
+	b, _ := json.Marshal(safe)
+	return b
+}
+
+func (s *Store) ListJobLogs(ctx context.Context, jobID string, afterID int64, limit int) ([]*core.JobLog, error) {
+	query := `
+		SELECT id, job_id, attempt, level, message, fields, logged_at
+		FROM wida_job_logs
+		WHERE job_id = $1 AND id > $2
+		ORDER BY id ASC
+		LIMIT $3
+	`
+	rows, err := s.pool.Query(ctx, query, jobID, afterID, limit)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return &job, nil
+	var logs []*core.JobLog
+	for rows.Next() {
+		var l core.JobLog
+		var fields []byte
+		if err := rows.Scan(&l.ID, &l.JobID, &l.Attempt, &l.Level, &l.Message, &fields, &l.Time); err != nil {
+			return nil, err
+		}
+		if fields != nil {
+			json.Unmarshal(fields, &l.Fields)
+		}
+		logs = append(logs, &l)
+	}
+	return logs, rows.Err()
 }
 
-func (s *Store) ListJobs(ctx context.Context, filter map[string]interface{}, limit, offset int) ([]*core.Job, error) {
+func (s *Store) WatchJob(ctx context.Context, id string) (<-chan struct{}, func(), error) {
+	return s.updated.subscribe(ctx, id)
+}
+
+func (s *Store) ListOverdue(ctx context.Context, margin time.Duration) ([]*core.Job, error) {
 	query := `
-		SELECT id, queue, payload, status, run_at, cron_expr, retry_policy, timeout, max_retries, attempts, dependencies, dependents
+		SELECT ` + jobColumns + `
 		FROM wida_jobs
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
+		WHERE status = 'running' AND timeout > 0 AND started_at IS NOT NULL
+		  AND started_at + make_interval(secs => (timeout + $1) / 1e9) < NOW()
 	`
-	rows, err := s.pool.Query(ctx, query, limit, offset)
+	rows, err := s.pool.Query(ctx, query, int64(margin))
 	if err != nil {
 		return nil, err
 	}
@@ -215,31 +592,140 @@ func (s *Store) ListJobs(ctx context.Context, filter map[string]interface{}, lim
 
 	var jobs []*core.Job
 	for rows.Next() {
-		var job core.Job
-		var payloadBytes, retryBytes, attemptsBytes, depsBytes, depsOutBytes []byte
-		var timeoutInt int64
-		var cronExpr *string
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
 
-		err := rows.Scan(
-			&job.ID, &job.Queue, &payloadBytes, &job.Status,
-			&job.RunAt, &cronExpr, &retryBytes, &timeoutInt,
-			&job.MaxRetries, &attemptsBytes, &depsBytes, &depsOutBytes,
+// Backlog applies the same filters as Dequeue, so jobs the worker could
+// never claim do not count.
+func (s *Store) Backlog(ctx context.Context, opts store.DequeueOptions) (store.Backlog, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(COALESCE(j.run_at, j.created_at))), 0)
+		FROM wida_jobs j
+		WHERE ` + readyJobs + `
+		  AND ($5 = 0 OR j.weight <= $5)
+	`
+	labels, _ := json.Marshal(opts.Labels)
+	var b store.Backlog
+	var waitSecs float64
+	err := s.pool.QueryRow(ctx, query, opts.Queues, opts.Executors, string(labels), int64(opts.PreferenceWait),
+		opts.MaxWeight).Scan(&b.Pending, &waitSecs)
+	if err != nil {
+		return store.Backlog{}, err
+	}
+	b.OldestWait = time.Duration(waitSecs * float64(time.Second))
+	return b, nil
+}
+
+func (s *Store) WaitForJob(ctx context.Context, id string) (*core.Job, error) {
+	wake, unsubscribe, err := s.finished.subscribe(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer unsubscribe()
+
+	for {
+		job, err := s.getJobOrDead(ctx, id)
+		if err != nil || job == nil || job.Status.Terminal() {
+			return job, err
+		}
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return job, ctx.Err()
+		}
+	}
+}
+
+// getJobOrDead looks a job up in wida_jobs and falls back to the DLQ, where
+// it is reported with status dead.
+func (s *Store) getJobOrDead(ctx context.Context, id string) (*core.Job, error) {
+	job, err := s.GetJob(ctx, id)
+	if err != nil || job != nil {
+		return job, err
+	}
+
+	var dead core.Job
+	var payloadBytes, attemptsBytes []byte
+	err = s.pool.QueryRow(ctx, `SELECT id, queue, payload, attempts FROM wida_dlq WHERE id = $1`, id).
+		Scan(&dead.ID, &dead.Queue, &payloadBytes, &attemptsBytes)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	dead.Status = core.StatusDead
+	json.Unmarshal(payloadBytes, &dead.Payload)
+	if attemptsBytes != nil {
+		json.Unmarshal(attemptsBytes, &dead.Attempts)
+	}
+	return &dead, nil
+}
+
+// jobTreeMaxDepth stops JobTree following parent links forever should
+// they ever form a cycle.
+const jobTreeMaxDepth = 32
+
+// JobTree returns the job and those it spawned, recursively, breadth first
+// and oldest first within each generation.
+func (s *Store) JobTree(ctx context.Context, rootID string, limit int) ([]*core.Job, error) {
+	query := `
+		WITH RECURSIVE tree (id, depth) AS (
+			SELECT id, 0 FROM wida_jobs WHERE id = $1
+			UNION ALL
+			SELECT j.id, t.depth + 1 FROM wida_jobs j
+			JOIN tree t ON j.parent_id = t.id
+			WHERE t.depth < $3
 		)
+		SELECT ` + jobColumns + `
+		FROM wida_jobs JOIN tree USING (id)
+		ORDER BY tree.depth, created_at, id
+		LIMIT $2
+	`
+	rows, err := s.pool.Query(ctx, query, rootID, limit, jobTreeMaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*core.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
 		if err != nil {
 			return nil, err
 		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
 
-		if cronExpr != nil {
-			job.CronExpr = *cronExpr
-		}
+func (s *Store) ListJobs(ctx context.Context, filter map[string]interface{}, limit, offset int) ([]*core.Job, error) {
+	query := `
+		SELECT ` + jobColumns + `
+		FROM wida_jobs
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2
+	`
+	rows, err := s.pool.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-		job.Timeout = time.Duration(timeoutInt)
-		json.Unmarshal(payloadBytes, &job.Payload)
-		json.Unmarshal(retryBytes, &job.RetryPolicy)
-		if attemptsBytes != nil {
-			json.Unmarshal(attemptsBytes, &job.Attempts)
+	var jobs []*core.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
 		}
-		jobs = append(jobs, &job)
+		jobs = append(jobs, job)
 	}
 
 	return jobs, nil
@@ -280,27 +766,149 @@ func (s *Store) ListDLQ(ctx context.Context, limit, offset int) ([]*core.DLQJob,
 	return jobs, nil
 }
 
-func (s *Store) RegisterWorker(ctx context.Context, workerID string) error {
+func (s *Store) RecordEvent(ctx context.Context, event *core.Event) error {
 	query := `
-		INSERT INTO wida_workers (id, status, last_heartbeat)
-		VALUES ($1, 'alive', NOW())
-		ON CONFLICT (id) DO UPDATE SET status = 'alive', last_heartbeat = NOW()
+		INSERT INTO wida_events (type, subject, message, node, data)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
 	`
-	_, err := s.pool.Exec(ctx, query, workerID)
+	return s.pool.QueryRow(ctx, query, event.Type, event.Subject, event.Message,
+		nullString(event.Node), nullJSON(event.Data)).Scan(&event.ID, &event.CreatedAt)
+}
+
+func (s *Store) ListEvents(ctx context.Context, eventType string, limit, offset int) ([]*core.Event, error) {
+	query := `
+		SELECT id, type, subject, message, node, data, created_at
+		FROM wida_events
+		WHERE $1 = '' OR type = $1
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := s.pool.Query(ctx, query, eventType, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*core.Event
+	for rows.Next() {
+		var e core.Event
+		var node *string
+		var data []byte
+		if err := rows.Scan(&e.ID, &e.Type, &e.Subject, &e.Message, &node, &data, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if node != nil {
+			e.Node = *node
+		}
+		if data != nil {
+			e.Data = data
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+// GetQueue returns the queue's definition, or nil if it has none.
+func (s *Store) GetQueue(ctx context.Context, name string) (*core.QueueConfig, error) {
+	query := `SELECT ` + queueColumns + ` FROM wida_queues WHERE name = $1`
+	q, err := scanQueue(s.pool.QueryRow(ctx, query, name))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return q, err
+}
+
+func (s *Store) ListQueues(ctx context.Context) ([]*core.QueueConfig, error) {
+	query := `SELECT ` + queueColumns + ` FROM wida_queues ORDER BY name ASC`
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var queues []*core.QueueConfig
+	for rows.Next() {
+		q, err := scanQueue(rows)
+		if err != nil {
+			return nil, err
+		}
+		queues = append(queues, q)
+	}
+	return queues, rows.Err()
+}
+
+func (s *Store) SaveQueue(ctx context.Context, q *core.QueueConfig) error {
+	query := `
+		INSERT INTO wida_queues (name, retry_policy, max_retries, timeout, priority, executor, retention)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (name) DO UPDATE SET
+		    retry_policy = EXCLUDED.retry_policy, max_retries = EXCLUDED.max_retries,
+		    timeout = EXCLUDED.timeout, priority = EXCLUDED.priority,
+		    executor = EXCLUDED.executor, retention = EXCLUDED.retention,
+		    updated_at = NOW()
+	`
+	retryBytes, _ := json.Marshal(q.RetryPolicy)
+	_, err := s.pool.Exec(ctx, query, q.Name, retryBytes, q.MaxRetries, int64(q.Timeout),
+		q.Priority, nullString(q.Executor), int64(q.Retention))
 	return err
 }
 
-func (s *Store) UpdateWorkerStatus(ctx context.Context, workerID string, status string, currentJobID string) error {
+func (s *Store) DeleteQueue(ctx context.Context, name string) (bool, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM wida_queues WHERE name = $1`, name)
+	if err != nil {
+		return false, err
+	}
+	return res.RowsAffected() > 0, nil
+}
+
+const queueColumns = `name, retry_policy, max_retries, timeout, priority, executor, retention`
+
+func scanQueue(row pgx.Row) (*core.QueueConfig, error) {
+	var q core.QueueConfig
+	var retryBytes []byte
+	var timeout, retention int64
+	var executor *string
+	if err := row.Scan(&q.Name, &retryBytes, &q.MaxRetries, &timeout, &q.Priority, &executor, &retention); err != nil {
+		return nil, err
+	}
+	q.Timeout = time.Duration(timeout)
+	q.Retention = time.Duration(retention)
+	if retryBytes != nil {
+		json.Unmarshal(retryBytes, &q.RetryPolicy)
+	}
+	if executor != nil {
+		q.Executor = *executor
+	}
+	return &q, nil
+}
+
+func (s *Store) RegisterWorker(ctx context.Context, workerID string, capacity int, labels core.Labels) error {
+	query := `
+		INSERT INTO wida_workers (id, status, capacity, active_jobs, labels, last_heartbeat)
+		VALUES ($1, 'alive', $2, 0, $3, NOW())
+		ON CONFLICT (id) DO UPDATE SET status = 'alive', capacity = $2, active_jobs = 0, used_slots = 0,
+		    labels = $3, current_job_id = NULL, last_heartbeat = NOW()
+	`
+	if labels == nil {
+		labels = core.Labels{}
+	}
+	labelBytes, _ := json.Marshal(labels)
+	_, err := s.pool.Exec(ctx, query, workerID, capacity, string(labelBytes))
+	return err
+}
+
+func (s *Store) UpdateWorkerStatus(ctx context.Context, workerID string, status string, currentJobID string, activeJobs, usedSlots int) error {
 	query := `
 		UPDATE wida_workers 
-		SET status = $1, current_job_id = $2, last_heartbeat = NOW()
+		SET status = $1, current_job_id = $2, active_jobs = $4, used_slots = $5, last_heartbeat = NOW()
 		WHERE id = $3
 	`
 	var jobID *string
 	if currentJobID != "" {
 		jobID = &currentJobID
 	}
-	_, err := s.pool.Exec(ctx, query, status, jobID, workerID)
+	_, err := s.pool.Exec(ctx, query, status, jobID, workerID, activeJobs, usedSlots)
 	return err
 }
 
@@ -312,7 +920,7 @@ func (s *Store) IncrementWorkerJobs(ctx context.Context, workerID string) error
 
 func (s *Store) ListWorkers(ctx context.Context) ([]*core.WorkerStats, error) {
 	query := `
-		SELECT id, status, current_job_id, jobs_completed, last_heartbeat
+		SELECT id, status, current_job_id, labels, capacity, active_jobs, used_slots, jobs_completed, last_heartbeat
 		FROM wida_workers
 		ORDER BY id ASC
 	`
@@ -326,11 +934,13 @@ func (s *Store) ListWorkers(ctx context.Context) ([]*core.WorkerStats, error) {
 	for rows.Next() {
 		var w core.WorkerStats
 		var currentJobID *string
+		var labels []byte
 
-		err := rows.Scan(&w.ID, &w.Status, &currentJobID, &w.JobsCompleted, &w.LastHeartbeat)
+		err := rows.Scan(&w.ID, &w.Status, &currentJobID, &labels, &w.Capacity, &w.ActiveJobs, &w.UsedSlots, &w.JobsCompleted, &w.LastHeartbeat)
 		if err != nil {
 			return nil, err
 		}
+		json.Unmarshal(labels, &w.Labels)
 		if currentJobID != nil {
 			w.CurrentJobID = *currentJobID
 		}
@@ -338,3 +948,105 @@ func (s *Store) ListWorkers(ctx context.Context) ([]*core.WorkerStats, error) {
 	}
 	return workers, nil
 }
+
+// jobColumns is the column list understood by scanJob.
+const jobColumns = `id, queue, payload, status, run_at, cron_expr, retry_policy, timeout, max_retries, attempts,
+	dependencies, dependents, concurrency_key, concurrency_limit, group_key, cancel_requested, result_ttl, result_expires_at,
+	executor, executor_config, job_type, worker_id, started_at, priority, required_labels, preferred_labels, weight, progress, parent_id`
+
+// scanJob scans the columns in jobColumns followed by any extra columns the
+// caller selected.
+func scanJob(row pgx.Row, extra ...any) (*core.Job, error) {
+	var job core.Job
+	var payloadBytes, retryBytes, attemptsBytes, depsBytes, depsOutBytes, executorConfig []byte
+	var requiredLabels, preferredLabels, progress []byte
+	var timeoutInt, resultTTL int64
+	var cronExpr, concurrencyKey, groupKey, jobType, workerID, parentID *string
+
+	dest := []any{
+		&job.ID, &job.Queue, &payloadBytes, &job.Status,
+		&job.RunAt, &cronExpr, &retryBytes, &timeoutInt,
+		&job.MaxRetries, &attemptsBytes, &depsBytes, &depsOutBytes,
+		&concurrencyKey, &job.ConcurrencyLimit, &groupKey, &job.CancelRequested,
+		&resultTTL, &job.ResultExpiresAt,
+		&job.Executor, &executorConfig, &jobType,
+		&workerID, &job.StartedAt, &job.Priority, &requiredLabels, &preferredLabels,
+		&job.Weight, &progress, &parentID,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	job.Timeout = time.Duration(timeoutInt)
+	job.ResultTTL = time.Duration(resultTTL)
+	if executorConfig != nil {
+		job.ExecutorConfig = executorConfig
+	}
+	json.Unmarshal(payloadBytes, &job.Payload)
+	json.Unmarshal(retryBytes, &job.RetryPolicy)
+	if requiredLabels != nil {
+		json.Unmarshal(requiredLabels, &job.RequiredLabels)
+	}
+	if progress != nil {
+		job.Progress = &core.Progress{}
+		json.Unmarshal(progress, job.Progress)
+	}
+	if preferredLabels != nil {
+		json.Unmarshal(preferredLabels, &job.PreferredLabels)
+	}
+	if attemptsBytes != nil {
+		json.Unmarshal(attemptsBytes, &job.Attempts)
+	}
+	if depsBytes != nil {
+		json.Unmarshal(depsBytes, &job.Dependencies)
+	}
+	if depsOutBytes != nil {
+		json.Unmarshal(depsOutBytes, &job.Dependents)
+	}
+	if cronExpr != nil {
+		job.CronExpr = *cronExpr
+	}
+	if concurrencyKey != nil {
+		job.ConcurrencyKey = *concurrencyKey
+	}
+	if groupKey != nil {
+		job.GroupKey = *groupKey
+	}
+	if jobType != nil {
+		job.Type = *jobType
+	}
+	if workerID != nil {
+		job.WorkerID = *workerID
+	}
+	if parentID != nil {
+		job.ParentID = *parentID
+	}
+
+	return &job, nil
+}
+
+// nullJSON maps an empty raw message to SQL NULL.
+func nullJSON(raw json.RawMessage) *string {
+	if len(raw) == 0 {
+		return nil
+	}
+	s := string(raw)
+	return &s
+}
+
+// labelsJSON encodes a label selector, with no labels as SQL NULL.
+func labelsJSON(l core.Labels) *string {
+	if len(l) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(l)
+	return nullJSON(b)
+}
+
+// nullString maps the empty string to SQL NULL.
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
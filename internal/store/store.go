@@ -2,22 +2,121 @@ package store
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/theb0imanuu/wida/internal/core"
 )
 
+// ErrNotCancellable is returned by Cancel for jobs that already finished.
+var ErrNotCancellable = errors.New("job is not pending or running")
+
+// ErrJobLost is returned when a worker tries to finish a job it no longer
+// holds: the job was finished by someone else, or timed out and claimed
+// again. The write is not made.
+var ErrJobLost = errors.New("job is no longer running on this worker")
+
+// DequeueOptions describes the worker asking for a job and what it can run.
+type DequeueOptions struct {
+	WorkerID string
+	Queues   []string
+	// QueueOrder makes Queues a precedence list: jobs from an earlier queue
+	// are claimed before any from a later one. Otherwise queues are ignored
+	// in ordering and the highest priority, oldest ready job wins.
+	QueueOrder bool
+	// Executors lists the executors registered on the worker; jobs for any
+	// other executor are left for another node.
+	Executors []string
+	// Labels are the worker's labels. Jobs whose required labels it lacks
+	// are never handed to it, and jobs whose preferred labels it lacks only
+	// once they have been ready for PreferenceWait.
+	Labels         core.Labels
+	PreferenceWait time.Duration
+	// MaxWeight skips jobs heavier than the worker's free slots; 0 means no
+	// limit.
+	MaxWeight int
+}
+
+// Backlog describes the ready jobs waiting in a set of queues.
+type Backlog struct {
+	Pending int
+	// OldestWait is how long the longest-waiting of them has been ready.
+	OldestWait time.Duration
+}
+
 // Store defines the interface for interacting with the queue datastore
 type Store interface {
 	Enqueue(ctx context.Context, job *core.Job) error
-	Dequeue(ctx context.Context, queues []string, workerID string) (*core.Job, error)
-	Heartbeat(ctx context.Context, jobID string, workerID string) error
-	UpdateStatus(ctx context.Context, jobID string, status core.Status, attempt *core.Attempt) error
-	MoveToDLQ(ctx context.Context, jobID string, reason string) error
+	// Dequeue claims the best ready job that fits opts, if any. With a
+	// MaxWeight, blocked is the weight of the top-ranked ready job when that
+	// job is too heavy to be claimed, and 0 otherwise. Claiming a job, like
+	// handing it back with Retry or Release, clears its progress.
+	Dequeue(ctx context.Context, opts DequeueOptions) (job *core.Job, blocked int, err error)
+	Heartbeat(ctx context.Context, jobID string, workerID string) (cancelRequested bool, err error)
+	// The writes that finish an attempt only apply while workerID still
+	// holds the job, returning ErrJobLost otherwise.
+	UpdateStatus(ctx context.Context, jobID, workerID string, status core.Status, attempt *core.Attempt) error
+	// Complete marks a running job successful and, in the same transaction,
+	// enqueues the jobs it spawned.
+	Complete(ctx context.Context, job *core.Job, workerID string, attempt *core.Attempt, spawned []*core.Job) error
+	// Retry makes the job runnable again at runAt, recording the failed
+	// attempt if one is given. Retry, Release and MoveToDLQ finish a job
+	// whose cancellation was requested while it ran as cancelled instead.
+	Retry(ctx context.Context, jobID, workerID string, attempt *core.Attempt, runAt time.Time) error
+	// Release hands a running job back to pending without counting it as a
+	// failure. The attempt, if any, is appended to its history.
+	Release(ctx context.Context, jobID, workerID string, attempt *core.Attempt) error
+	// MoveToDLQ records the final attempt and moves the job to the DLQ.
+	MoveToDLQ(ctx context.Context, jobID, workerID string, attempt *core.Attempt, reason string) error
+	// FailPending moves a pending job that can never run, such as one whose
+	// dependency died, to the DLQ. It returns ErrJobLost if the job is no
+	// longer pending.
+	FailPending(ctx context.Context, jobID, reason string) error
+	Cancel(ctx context.Context, jobID string) (*core.Job, error)
 	GetJob(ctx context.Context, id string) (*core.Job, error)
+	// UpdateProgress and SaveCheckpoint record what a running job reports
+	// about itself. They do nothing unless workerID still holds the job.
+	UpdateProgress(ctx context.Context, jobID, workerID string, progress core.Progress) error
+	SaveCheckpoint(ctx context.Context, jobID, workerID string, data []byte) error
+	// AppendJobLogs stores log lines written by running jobs.
+	AppendJobLogs(ctx context.Context, logs []core.JobLog) error
+	// ListJobLogs returns up to limit of the job's log lines with an ID
+	// above afterID, oldest first.
+	ListJobLogs(ctx context.Context, jobID string, afterID int64, limit int) ([]*core.JobLog, error)
+	// WatchJob signals changed whenever the job's status or progress
+	// changes, until stop is called. It only returns once watching, so the
+	// job can be read afterwards without missing a change.
+	WatchJob(ctx context.Context, id string) (changed <-chan struct{}, stop func(), err error)
+	// ListOverdue returns running jobs that have outlived their timeout by
+	// more than margin.
+	ListOverdue(ctx context.Context, margin time.Duration) ([]*core.Job, error)
+	// Backlog counts the ready pending jobs that a worker described by opts
+	// could claim, with MaxWeight its total capacity.
+	Backlog(ctx context.Context, opts DequeueOptions) (Backlog, error)
+	// WaitForJob blocks until the job reaches a terminal status, returning it
+	// (a job moved to the DLQ comes back as dead). If ctx ends first it
+	// returns the job's latest state along with ctx.Err().
+	WaitForJob(ctx context.Context, id string) (*core.Job, error)
+	// JobTree returns the job followed by up to limit-1 of the jobs it
+	// spawned, their children and so on, parents before children.
+	JobTree(ctx context.Context, rootID string, limit int) ([]*core.Job, error)
 	ListJobs(ctx context.Context, filter map[string]interface{}, limit, offset int) ([]*core.Job, error)
 	ListDLQ(ctx context.Context, limit, offset int) ([]*core.DLQJob, error)
-	RegisterWorker(ctx context.Context, workerID string) error
-	UpdateWorkerStatus(ctx context.Context, workerID string, status string, currentJobID string) error
+	// Queue definitions hold the defaults Enqueue applies to their jobs.
+	GetQueue(ctx context.Context, name string) (*core.QueueConfig, error)
+	ListQueues(ctx context.Context) ([]*core.QueueConfig, error)
+	SaveQueue(ctx context.Context, q *core.QueueConfig) error
+	// DeleteQueue reports whether the queue had a definition.
+	DeleteQueue(ctx context.Context, name string) (bool, error)
+
+	// RecordEvent stores an event, setting its ID and CreatedAt.
+	RecordEvent(ctx context.Context, event *core.Event) error
+	// ListEvents returns the newest events first, only those of eventType
+	// unless it is empty.
+	ListEvents(ctx context.Context, eventType string, limit, offset int) ([]*core.Event, error)
+
+	RegisterWorker(ctx context.Context, workerID string, capacity int, labels core.Labels) error
+	UpdateWorkerStatus(ctx context.Context, workerID string, status string, currentJobID string, activeJobs, usedSlots int) error
 	IncrementWorkerJobs(ctx context.Context, workerID string) error
 	ListWorkers(ctx context.Context) ([]*core.WorkerStats, error)
 }
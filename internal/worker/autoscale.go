@@ -0,0 +1,264 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/store"
+)
+
+// Autoscaling defaults, used for zero fields of an AutoscaleConfig.
+const (
+	DefaultScaleInterval  = 15 * time.Second
+	DefaultScaleDownDelay = 5 * time.Minute
+	DefaultTargetWait     = 30 * time.Second
+)
+
+// maxScalingDecisions is how many recent decisions ScalingStatus keeps.
+const maxScalingDecisions = 20
+
+// latencyAlpha weighs each new execution in the pool's latency average.
+const latencyAlpha = 0.2
+
+// AutoscaleConfig lets the pool add and remove workers between Min and Max.
+// Every Interval it compares the ready backlog of its queues against how
+// fast its workers are getting through it.
+type AutoscaleConfig struct {
+	Min, Max int
+	Interval time.Duration
+
+	// TargetWait is how long a ready job should wait at most. The pool scales
+	// up when the oldest ready job has waited longer, or when the backlog at
+	// the current executor latency would take longer to work off.
+	TargetWait time.Duration
+
+	// ScaleDownDelay is how long the pool must have had nothing to wait on and
+	// spare slots before it removes a worker.
+	ScaleDownDelay time.Duration
+}
+
+func (c AutoscaleConfig) withDefaults() AutoscaleConfig {
+	if c.Min < 1 {
+		c.Min = 1
+	}
+	if c.Max < c.Min {
+		c.Max = c.Min
+	}
+	if c.Interval <= 0 {
+		c.Interval = DefaultScaleInterval
+	}
+	if c.TargetWait <= 0 {
+		c.TargetWait = DefaultTargetWait
+	}
+	if c.ScaleDownDelay <= 0 {
+		c.ScaleDownDelay = DefaultScaleDownDelay
+	}
+	return c
+}
+
+// ScalingDecision records the pool changing its number of workers.
+type ScalingDecision struct {
+	Time   time.Time `json:"time"`
+	From   int       `json:"from"`
+	To     int       `json:"to"`
+	Reason string    `json:"reason"`
+}
+
+// ScalingStatus is the autoscaler's view of the pool, as shown by the
+// workers API.
+type ScalingStatus struct {
+	Min       int               `json:"min"`
+	Max       int               `json:"max"`
+	Workers   int               `json:"workers"`
+	Latency   time.Duration     `json:"latency"`
+	Decisions []ScalingDecision `json:"decisions"`
+}
+
+// scaleSignals is what a scaling decision is based on.
+type scaleSignals struct {
+	store.Backlog
+	Workers  int
+	Slots    int // per worker
+	Used     int // slots in use across the pool
+	Latency  time.Duration
+	LowSince time.Time // zero unless the pool has been underused
+}
+
+// decideScale returns how many workers the pool should run and why, or the
+// current number and "" to leave it alone. now is compared to LowSince.
+func decideScale(cfg AutoscaleConfig, s scaleSignals, now time.Time) (int, string) {
+	current := s.Workers
+	if current < cfg.Min {
+		return cfg.Min, fmt.Sprintf("below the minimum of %d workers", cfg.Min)
+	}
+	if current > cfg.Max {
+		return cfg.Max, fmt.Sprintf("above the maximum of %d workers", cfg.Max)
+	}
+
+	if s.Pending > 0 && current < cfg.Max {
+		// Workers needed to work off the backlog within TargetWait at the
+		// current latency.
+		needed := current
+		if s.Latency > 0 && s.Slots > 0 {
+			perWorker := float64(s.Slots) * float64(cfg.TargetWait) / float64(s.Latency)
+			needed = int(math.Ceil(float64(s.Pending) / perWorker))
+		}
+		switch {
+		case s.OldestWait > cfg.TargetWait:
+			target := min(max(needed, current+1), cfg.Max)
+			return target, fmt.Sprintf("%d ready jobs, oldest waiting %v (target %v)",
+				s.Pending, s.OldestWait.Round(time.Second), cfg.TargetWait)
+		case needed > current:
+			target := min(needed, cfg.Max)
+			return target, fmt.Sprintf("%d ready jobs at %v each need %d workers to clear in %v",
+				s.Pending, s.Latency.Round(time.Millisecond), needed, cfg.TargetWait)
+		}
+	}
+
+	if current > cfg.Min && !s.LowSince.IsZero() && now.Sub(s.LowSince) >= cfg.ScaleDownDelay {
+		return current - 1, fmt.Sprintf("no backlog and %d of %d slots busy for %v",
+			s.Used, current*s.Slots, now.Sub(s.LowSince).Round(time.Second))
+	}
+	return current, ""
+}
+
+// underused reports whether the pool could lose a worker without its
+// running jobs having to wait: nothing is ready and the busy slots would fit
+// in one worker fewer.
+func underused(s scaleSignals) bool {
+	return s.Pending == 0 && s.Used <= (s.Workers-1)*s.Slots
+}
+
+// autoscaler holds the state behind the pool's scaling decisions.
+type autoscaler struct {
+	cfg AutoscaleConfig
+
+	mu        sync.Mutex
+	latency   time.Duration
+	lowSince  time.Time
+	decisions []ScalingDecision
+}
+
+// observe folds an execution's duration into the latency average.
+func (a *autoscaler) observe(d time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.latency == 0 {
+		a.latency = d
+		return
+	}
+	a.latency = time.Duration(latencyAlpha*float64(d) + (1-latencyAlpha)*float64(a.latency))
+}
+
+func (a *autoscaler) record(d ScalingDecision) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.decisions = append(a.decisions, d)
+	if len(a.decisions) > maxScalingDecisions {
+		a.decisions = a.decisions[len(a.decisions)-maxScalingDecisions:]
+	}
+}
+
+// autoscale adjusts the number of workers every Interval until the pool is
+// stopped.
+func (p *Pool) autoscale(ctx context.Context) {
+	defer close(p.scaleDone)
+
+	ticker := time.NewTicker(p.scaler.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.stopping:
+			return
+		case <-ticker.C:
+			p.scale(ctx)
+		}
+	}
+}
+
+func (p *Pool) scale(ctx context.Context) {
+	// Only jobs this pool could claim are backlog: more workers do not help
+	// with jobs for other executors or labels, too heavy for a worker, or
+	// held back by a breaker.
+	queues, executors := p.Queues, p.executorNames()
+	if p.Breakers != nil {
+		queues, executors = p.Breakers.Closed(queues, executors)
+	}
+	var backlog store.Backlog
+	if len(queues) > 0 && len(executors) > 0 {
+		var err error
+		backlog, err = p.Store.Backlog(ctx, store.DequeueOptions{
+			Queues:         queues,
+			Executors:      executors,
+			Labels:         p.Labels,
+			PreferenceWait: p.PreferenceWait,
+			MaxWeight:      p.concurrency,
+		})
+		if err != nil {
+			log.Printf("Worker pool %s autoscale error: %v\n", p.ID, err)
+			return
+		}
+	}
+
+	workers := p.activeWorkers()
+	s := scaleSignals{Backlog: backlog, Workers: len(workers), Slots: p.concurrency}
+	for _, w := range workers {
+		s.Used += w.Used()
+	}
+
+	a := p.scaler
+	now := time.Now()
+	a.mu.Lock()
+	s.Latency = a.latency
+	if !underused(s) {
+		a.lowSince = time.Time{}
+	} else if a.lowSince.IsZero() {
+		a.lowSince = now
+	}
+	s.LowSince = a.lowSince
+	a.mu.Unlock()
+
+	target, reason := decideScale(a.cfg, s, now)
+	if target == s.Workers {
+		return
+	}
+
+	log.Printf("Worker pool %s scaling from %d to %d workers: %s\n", p.ID, s.Workers, target, reason)
+	a.record(ScalingDecision{Time: now, From: s.Workers, To: target, Reason: reason})
+	for n := s.Workers; n < target; n++ {
+		p.addWorker(ctx)
+	}
+	for n := s.Workers; n > target; n-- {
+		p.removeWorker()
+	}
+
+	// Give the new size a full delay before shrinking again.
+	a.mu.Lock()
+	a.lowSince = time.Time{}
+	a.mu.Unlock()
+}
+
+// ScalingStatus reports the autoscaler's settings and recent decisions, or
+// nil if the pool is not autoscaled.
+func (p *Pool) ScalingStatus() *ScalingStatus {
+	a := p.scaler
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return &ScalingStatus{
+		Min:       a.cfg.Min,
+		Max:       a.cfg.Max,
+		Workers:   len(p.activeWorkers()),
+		Latency:   a.latency,
+		Decisions: append([]ScalingDecision(nil), a.decisions...),
+	}
+}
@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/store"
+)
+
+func TestDecideScale(t *testing.T) {
+	cfg := AutoscaleConfig{Min: 1, Max: 10}.withDefaults()
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		s    scaleSignals
+		want int
+	}{
+		{"steady", scaleSignals{Workers: 2, Slots: 5, Used: 6}, 2},
+		{"below min", scaleSignals{Workers: 0, Slots: 5}, 1},
+		{"waited too long", scaleSignals{
+			Backlog: store.Backlog{Pending: 3, OldestWait: time.Minute},
+			Workers: 2, Slots: 5, Used: 10,
+		}, 3},
+		// 200 jobs at 1s each need 200/(5*30) = 2 workers to clear in 30s.
+		{"backlog at latency", scaleSignals{
+			Backlog: store.Backlog{Pending: 200, OldestWait: time.Second},
+			Workers: 1, Slots: 5, Used: 5, Latency: time.Second,
+		}, 2},
+		{"capped at max", scaleSignals{
+			Backlog: store.Backlog{Pending: 10000, OldestWait: time.Hour},
+			Workers: 4, Slots: 5, Used: 20, Latency: time.Second,
+		}, 10},
+		{"idle but not for long", scaleSignals{Workers: 3, Slots: 5, LowSince: now.Add(-time.Minute)}, 3},
+		{"idle", scaleSignals{Workers: 3, Slots: 5, Used: 1, LowSince: now.Add(-time.Hour)}, 2},
+		{"idle at min", scaleSignals{Workers: 1, Slots: 5, LowSince: now.Add(-time.Hour)}, 1},
+	}
+	for _, tt := range tests {
+		got, reason := decideScale(cfg, tt.s, now)
+		if got != tt.want {
+			t.Errorf("%s: scaled to %d (%s), want %d", tt.name, got, reason, tt.want)
+		}
+		if (got != tt.s.Workers) != (reason != "") {
+			t.Errorf("%s: scaled to %d with reason %q", tt.name, got, reason)
+		}
+	}
+}
+
+func TestUnderused(t *testing.T) {
+	if !underused(scaleSignals{Workers: 3, Slots: 5, Used: 10}) {
+		t.Error("busy slots fit in one worker fewer but pool not underused")
+	}
+	if underused(scaleSignals{Workers: 3, Slots: 5, Used: 11}) {
+		t.Error("pool underused with more busy slots than one worker fewer holds")
+	}
+	if underused(scaleSignals{Backlog: store.Backlog{Pending: 1}, Workers: 3, Slots: 5}) {
+		t.Error("pool underused with ready jobs waiting")
+	}
+}
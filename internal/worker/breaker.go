@@ -0,0 +1,320 @@
+package worker
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState string
+
+const (
+	// BreakerClosed lets jobs be claimed as usual.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen stops the node claiming jobs until the cool-down is over.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a few trial jobs through to decide whether to
+	// close the breaker again or reopen it.
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// BreakerConfig sets when a breaker trips and how it recovers.
+type BreakerConfig struct {
+	// Window is how many of the most recent executions the failure rate is
+	// taken over, and MinExecutions how many it needs before it can trip.
+	Window        int
+	MinExecutions int
+	FailureRate   float64
+
+	// Cooldown is how long the breaker stays open before it lets Probes
+	// trial jobs through. All of them must succeed for it to close.
+	Cooldown time.Duration
+	Probes   int
+
+	// ExecutorQueues is how many queues the failures must come from for an
+	// executor's breaker to trip, so that one queue's bad jobs do not stop
+	// the executor for every other queue. That queue's own breaker trips.
+	ExecutorQueues int
+}
+
+// DefaultBreakerConfig trips a breaker when half of at least 10 of the last
+// 20 executions failed, across at least 2 queues for an executor.
+var DefaultBreakerConfig = BreakerConfig{
+	Window:         20,
+	MinExecutions:  10,
+	FailureRate:    0.5,
+	Cooldown:       30 * time.Second,
+	Probes:         1,
+	ExecutorQueues: 2,
+}
+
+// Outcome is how an execution bears on a breaker.
+type Outcome int
+
+const (
+	// OutcomeNeutral says nothing about the dependency's health: the job was
+	// cancelled, interrupted, snoozed or rejected as permanently invalid.
+	OutcomeNeutral Outcome = iota
+	OutcomeSuccess
+	OutcomeFailure
+)
+
+// BreakerTransition describes a breaker changing state.
+type BreakerTransition struct {
+	Key      string
+	From, To BreakerState
+	Reason   string
+}
+
+// Breakers holds a circuit breaker per queue and per executor. While either
+// breaker for a job is open the node does not claim it, so it stays pending
+// without losing an attempt.
+type Breakers struct {
+	cfg BreakerConfig
+
+	// OnTransition, if set, is called after every state change.
+	OnTransition func(BreakerTransition)
+
+	now func() time.Time
+
+	mu     sync.Mutex
+	byKey  map[string]*breaker
+	probes map[string][]string // job ID -> keys it is a trial job for
+}
+
+type breaker struct {
+	state    BreakerState
+	outcomes []execution // recent executions
+	openedAt time.Time
+	inFlight int // trial jobs claimed or being claimed
+	passed   int // trial jobs that succeeded
+}
+
+type execution struct {
+	queue  string
+	failed bool
+}
+
+func NewBreakers(cfg BreakerConfig) *Breakers {
+	if cfg.Window < 1 {
+		cfg.Window = DefaultBreakerConfig.Window
+	}
+	if cfg.MinExecutions < 1 || cfg.MinExecutions > cfg.Window {
+		cfg.MinExecutions = cfg.Window
+	}
+	if cfg.FailureRate <= 0 {
+		cfg.FailureRate = DefaultBreakerConfig.FailureRate
+	}
+	if cfg.Probes < 1 {
+		cfg.Probes = 1
+	}
+	if cfg.ExecutorQueues < 1 {
+		cfg.ExecutorQueues = DefaultBreakerConfig.ExecutorQueues
+	}
+	return &Breakers{
+		cfg:    cfg,
+		now:    time.Now,
+		byKey:  make(map[string]*breaker),
+		probes: make(map[string][]string),
+	}
+}
+
+func queueKey(name string) string    { return "queue:" + name }
+func executorKey(name string) string { return "executor:" + name }
+
+// jobKeys are the breakers a job's execution counts towards.
+func jobKeys(job *core.Job) []string {
+	return []string{queueKey(job.Queue), executorKey(job.Executor)}
+}
+
+func (b *Breakers) get(key string) *breaker {
+	br, ok := b.byKey[key]
+	if !ok {
+		br = &breaker{state: BreakerClosed}
+		b.byKey[key] = br
+	}
+	return br
+}
+
+// States returns the state of every breaker that is not closed, by key.
+func (b *Breakers) States() map[string]BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	states := make(map[string]BreakerState)
+	for key, br := range b.byKey {
+		if br.state != BreakerClosed {
+			states[key] = br.state
+		}
+	}
+	return states
+}
+
+// Closed filters queues and executors down to those whose breakers are
+// closed. Unlike Admit it changes nothing.
+func (b *Breakers) Closed(queues, executors []string) (okQueues, okExecutors []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	closed := func(key string) bool {
+		br, ok := b.byKey[key]
+		return !ok || br.state == BreakerClosed
+	}
+	for _, q := range queues {
+		if closed(queueKey(q)) {
+			okQueues = append(okQueues, q)
+		}
+	}
+	for _, e := range executors {
+		if closed(executorKey(e)) {
+			okExecutors = append(okExecutors, e)
+		}
+	}
+	return okQueues, okExecutors
+}
+
+// Admit filters queues and executors down to those whose breakers let the
+// node claim a job. For each half-open breaker let through it reserves a
+// trial; those go back with Claimed once the claim is made.
+func (b *Breakers) Admit(queues, executors []string) (okQueues, okExecutors, probes []string) {
+	b.mu.Lock()
+	var ts []BreakerTransition
+	admit := func(key string) bool {
+		br := b.get(key)
+		if br.state == BreakerOpen && b.now().Sub(br.openedAt) >= b.cfg.Cooldown {
+			ts = append(ts, b.transition(key, br, BreakerHalfOpen,
+				fmt.Sprintf("cool-down of %v over, letting %d trial jobs through", b.cfg.Cooldown, b.cfg.Probes)))
+		}
+		switch br.state {
+		case BreakerClosed:
+			return true
+		case BreakerHalfOpen:
+			if br.inFlight+br.passed < b.cfg.Probes {
+				br.inFlight++
+				probes = append(probes, key)
+				return true
+			}
+		}
+		return false
+	}
+	for _, q := range queues {
+		if admit(queueKey(q)) {
+			okQueues = append(okQueues, q)
+		}
+	}
+	for _, e := range executors {
+		if admit(executorKey(e)) {
+			okExecutors = append(okExecutors, e)
+		}
+	}
+	b.mu.Unlock()
+
+	b.notify(ts)
+	return okQueues, okExecutors, probes
+}
+
+// Claimed settles the trials reserved by Admit: those for job's breakers
+// are kept until Record, the rest are given back. job may be nil if nothing
+// was claimed.
+func (b *Breakers) Claimed(job *core.Job, probes []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var kept []string
+	for _, key := range probes {
+		if job != nil && (key == queueKey(job.Queue) || key == executorKey(job.Executor)) {
+			kept = append(kept, key)
+			continue
+		}
+		b.get(key).inFlight--
+	}
+	if len(kept) > 0 {
+		b.probes[job.ID] = kept
+	}
+}
+
+// Record counts a finished execution of job towards its breakers.
+func (b *Breakers) Record(job *core.Job, outcome Outcome) {
+	b.mu.Lock()
+	var ts []BreakerTransition
+
+	probes := b.probes[job.ID]
+	delete(b.probes, job.ID)
+
+	for _, key := range jobKeys(job) {
+		br := b.get(key)
+		if slices.Contains(probes, key) {
+			br.inFlight--
+			if br.state != BreakerHalfOpen {
+				continue
+			}
+			switch outcome {
+			case OutcomeFailure:
+				ts = append(ts, b.transition(key, br, BreakerOpen, fmt.Sprintf("trial job %s failed", job.ID)))
+			case OutcomeSuccess:
+				if br.passed++; br.passed >= b.cfg.Probes {
+					ts = append(ts, b.transition(key, br, BreakerClosed, fmt.Sprintf("%d trial jobs succeeded", br.passed)))
+				}
+			}
+			continue
+		}
+
+		// Jobs claimed before the breaker tripped say nothing new.
+		if br.state != BreakerClosed || outcome == OutcomeNeutral {
+			continue
+		}
+		br.outcomes = append(br.outcomes, execution{queue: job.Queue, failed: outcome == OutcomeFailure})
+		if len(br.outcomes) > b.cfg.Window {
+			br.outcomes = br.outcomes[len(br.outcomes)-b.cfg.Window:]
+		}
+		failed, queues := countFailed(br.outcomes)
+		if key == executorKey(job.Executor) && queues < b.cfg.ExecutorQueues {
+			continue
+		}
+		if n := len(br.outcomes); n >= b.cfg.MinExecutions && float64(failed)/float64(n) >= b.cfg.FailureRate {
+			ts = append(ts, b.transition(key, br, BreakerOpen,
+				fmt.Sprintf("%d of the last %d executions failed", failed, n)))
+		}
+	}
+	b.mu.Unlock()
+
+	b.notify(ts)
+}
+
+// transition moves br to state, resetting what the new state counts.
+func (b *Breakers) transition(key string, br *breaker, to BreakerState, reason string) BreakerTransition {
+	t := BreakerTransition{Key: key, From: br.state, To: to, Reason: reason}
+	br.state = to
+	br.passed = 0
+	switch to {
+	case BreakerOpen:
+		br.openedAt = b.now()
+	case BreakerClosed:
+		br.outcomes = nil
+	}
+	return t
+}
+
+func (b *Breakers) notify(ts []BreakerTransition) {
+	if b.OnTransition == nil {
+		return
+	}
+	for _, t := range ts {
+		b.OnTransition(t)
+	}
+}
+
+// countFailed returns how many of outcomes failed and how many queues the
+// failures came from.
+func countFailed(outcomes []execution) (failed, queues int) {
+	seen := make(map[string]bool)
+	for _, e := range outcomes {
+		if e.failed {
+			failed++
+			seen[e.queue] = true
+		}
+	}
+	return failed, len(seen)
+}
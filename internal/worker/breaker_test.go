@@ -0,0 +1,130 @@
+package worker
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+func TestBreakerTripsAndRecovers(t *testing.T) {
+	b := NewBreakers(BreakerConfig{Window: 4, MinExecutions: 4, FailureRate: 0.5, Cooldown: time.Minute, Probes: 1})
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	var transitions []string
+	b.OnTransition = func(t BreakerTransition) {
+		transitions = append(transitions, fmt.Sprintf("%s %s->%s", t.Key, t.From, t.To))
+	}
+
+	queues, executors := []string{"emails", "reports"}, []string{"http"}
+	// The jobs are spread over executors so that only the queue's breaker
+	// sees enough of them to trip.
+	n := 0
+	run := func(queue string, outcome Outcome) {
+		n++
+		job := &core.Job{ID: fmt.Sprintf("job-%d", n), Queue: queue, Executor: fmt.Sprintf("exec-%d", n%4)}
+		b.Claimed(job, nil)
+		b.Record(job, outcome)
+	}
+
+	// Neutral outcomes, like cancellations, are not failures.
+	for i := 0; i < 4; i++ {
+		run("emails", OutcomeNeutral)
+	}
+	run("emails", OutcomeSuccess)
+	run("emails", OutcomeSuccess)
+	run("emails", OutcomeFailure)
+	if len(transitions) != 0 {
+		t.Fatalf("breaker tripped early: %v", transitions)
+	}
+	run("emails", OutcomeFailure)
+	if want := []string{"queue:emails closed->open"}; !reflect.DeepEqual(transitions, want) {
+		t.Fatalf("transitions = %v, want %v", transitions, want)
+	}
+
+	// The open queue is no longer claimed from.
+	okQueues, okExecutors, probes := b.Admit(queues, executors)
+	if !reflect.DeepEqual(okQueues, []string{"reports"}) || !reflect.DeepEqual(okExecutors, executors) || len(probes) != 0 {
+		t.Fatalf("Admit = %v, %v, %v while emails is open", okQueues, okExecutors, probes)
+	}
+
+	// After the cool-down one trial job is let through at a time.
+	now = now.Add(time.Minute)
+	okQueues, _, probes = b.Admit(queues, executors)
+	if !reflect.DeepEqual(okQueues, queues) || !reflect.DeepEqual(probes, []string{"queue:emails"}) {
+		t.Fatalf("Admit after cool-down = %v with probes %v", okQueues, probes)
+	}
+	if okQueues, _, _ := b.Admit(queues, executors); !reflect.DeepEqual(okQueues, []string{"reports"}) {
+		t.Fatalf("second trial admitted while the first is in flight: %v", okQueues)
+	}
+	if okQueues, _ := b.Closed(queues, executors); !reflect.DeepEqual(okQueues, []string{"reports"}) {
+		t.Fatalf("Closed = %v while emails is half-open", okQueues)
+	}
+
+	// A failed trial reopens the breaker.
+	trial := &core.Job{ID: "trial-1", Queue: "emails", Executor: "http"}
+	b.Claimed(trial, probes)
+	b.Record(trial, OutcomeFailure)
+	if got := b.States()["queue:emails"]; got != BreakerOpen {
+		t.Fatalf("breaker %s after failed trial, want open", got)
+	}
+
+	// A trial that was reserved but not claimed is handed back.
+	now = now.Add(time.Minute)
+	_, _, probes = b.Admit(queues, executors)
+	b.Claimed(&core.Job{ID: "other", Queue: "reports", Executor: "http"}, probes)
+	_, _, probes = b.Admit(queues, executors)
+	if len(probes) != 1 {
+		t.Fatalf("unclaimed trial not handed back, probes = %v", probes)
+	}
+
+	// A successful trial closes it.
+	trial = &core.Job{ID: "trial-2", Queue: "emails", Executor: "http"}
+	b.Claimed(trial, probes)
+	b.Record(trial, OutcomeSuccess)
+	if states := b.States(); len(states) != 0 {
+		t.Fatalf("breakers not closed after successful trial: %v", states)
+	}
+
+	want := []string{
+		"queue:emails closed->open",
+		"queue:emails open->half-open",
+		"queue:emails half-open->open",
+		"queue:emails open->half-open",
+		"queue:emails half-open->closed",
+	}
+	if !reflect.DeepEqual(transitions, want) {
+		t.Errorf("transitions = %v, want %v", transitions, want)
+	}
+}
+
+func TestExecutorBreakerNeedsSeveralQueues(t *testing.T) {
+	b := NewBreakers(BreakerConfig{Window: 4, MinExecutions: 4, FailureRate: 0.5, Cooldown: time.Minute})
+	n := 0
+	run := func(queue string) {
+		n++
+		job := &core.Job{ID: fmt.Sprintf("job-%d", n), Queue: queue, Executor: "http"}
+		b.Claimed(job, nil)
+		b.Record(job, OutcomeFailure)
+	}
+
+	// One queue failing trips only that queue's breaker.
+	for i := 0; i < 4; i++ {
+		run("emails")
+	}
+	want := map[string]BreakerState{"queue:emails": BreakerOpen}
+	if states := b.States(); !reflect.DeepEqual(states, want) {
+		t.Fatalf("states = %v, want %v", states, want)
+	}
+	if _, executors := b.Closed([]string{"reports"}, []string{"http"}); len(executors) != 1 {
+		t.Fatal("http executor blocked for every queue by one failing queue")
+	}
+
+	// Failures from a second queue show the executor itself is failing.
+	run("reports")
+	if got := b.States()["executor:http"]; got != BreakerOpen {
+		t.Errorf("executor breaker %s after failures in two queues, want open", got)
+	}
+}
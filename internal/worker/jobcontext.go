@@ -0,0 +1,150 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/core"
+	"github.com/theb0imanuu/wida/internal/store"
+)
+
+// jobContext is the core.JobContext a job executes with. Its writes only
+// land while the worker still holds the job.
+type jobContext struct {
+	ctx      context.Context
+	store    store.Store
+	jobID    string
+	queue    string
+	executor string
+	workerID string
+	logger   *slog.Logger
+
+	mu         sync.Mutex
+	checkpoint []byte
+	children   []*core.Job
+	next       *core.Job
+}
+
+func newJobContext(ctx context.Context, s store.Store, job *core.Job, workerID string, logs *jobLogBuffer) *jobContext {
+	return &jobContext{
+		ctx:        ctx,
+		store:      s,
+		jobID:      job.ID,
+		queue:      job.Queue,
+		executor:   job.Executor,
+		workerID:   workerID,
+		logger:     slog.New(&jobLogHandler{buf: logs, attempt: len(job.Attempts) + 1}),
+		checkpoint: job.Checkpoint,
+	}
+}
+
+func (jc *jobContext) Logger() *slog.Logger {
+	return jc.logger
+}
+
+func (jc *jobContext) ReportProgress(percent float64, message string) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("progress %v%% is outside 0-100", percent)
+	}
+	return jc.store.UpdateProgress(jc.ctx, jc.jobID, jc.workerID, core.Progress{
+		Percent:   percent,
+		Message:   message,
+		UpdatedAt: time.Now(),
+	})
+}
+
+func (jc *jobContext) SaveCheckpoint(data []byte) error {
+	data = append([]byte(nil), data...)
+	if err := jc.store.SaveCheckpoint(jc.ctx, jc.jobID, jc.workerID, data); err != nil {
+		return err
+	}
+	jc.mu.Lock()
+	jc.checkpoint = data
+	jc.mu.Unlock()
+	return nil
+}
+
+func (jc *jobContext) Checkpoint() []byte {
+	jc.mu.Lock()
+	defer jc.mu.Unlock()
+	return jc.checkpoint
+}
+
+func (jc *jobContext) Spawn(children ...*core.Job) error {
+	jc.mu.Lock()
+	defer jc.mu.Unlock()
+	n := len(jc.children)
+	for _, child := range children {
+		n++
+		c, err := jc.child(child, strconv.Itoa(n))
+		if err != nil {
+			return err
+		}
+		jc.children = append(jc.children, c)
+	}
+	return nil
+}
+
+func (jc *jobContext) ContinueWith(next *core.Job) error {
+	c, err := jc.child(next, "then")
+	if err != nil {
+		return err
+	}
+	jc.mu.Lock()
+	jc.next = c
+	jc.mu.Unlock()
+	return nil
+}
+
+// child copies a job spawned by this one, filling in what it leaves out.
+func (jc *jobContext) child(job *core.Job, suffix string) (*core.Job, error) {
+	if job == nil {
+		return nil, errors.New("spawned job is nil")
+	}
+	if err := job.RetryPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	c := *job
+	c.ParentID = jc.jobID
+	if c.ID == "" {
+		c.ID = jc.jobID + "." + suffix
+	}
+	if c.Queue == "" {
+		c.Queue = jc.queue
+	}
+	if c.Executor == "" {
+		c.Executor = jc.executor
+	}
+	c.Status = core.StatusPending
+	return &c, nil
+}
+
+// spawned returns the jobs to enqueue if the attempt succeeds: the children,
+// then the continuation waiting on all of them.
+func (jc *jobContext) spawned() []*core.Job {
+	jc.mu.Lock()
+	defer jc.mu.Unlock()
+	jobs := make([]*core.Job, 0, len(jc.children)+1)
+	for _, child := range jc.children {
+		c := *child
+		if jc.next != nil {
+			c.Dependents = append(slices.Clone(c.Dependents), jc.next.ID)
+		}
+		jobs = append(jobs, &c)
+	}
+	if jc.next != nil {
+		next := *jc.next
+		next.Dependencies = slices.Clone(next.Dependencies)
+		for _, child := range jc.children {
+			next.Dependencies = append(next.Dependencies, child.ID)
+		}
+		jobs = append(jobs, &next)
+	}
+	return jobs
+}
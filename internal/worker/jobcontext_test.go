@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+func TestJobContextSpawn(t *testing.T) {
+	parent := &core.Job{ID: "crawl", Queue: "crawls", Executor: "handlers"}
+	jc := newJobContext(context.Background(), nil, parent, "w1", nil)
+
+	if err := jc.Spawn(&core.Job{Type: "fetch"}, &core.Job{ID: "custom", Queue: "fetches", Type: "fetch"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := jc.Spawn(&core.Job{Type: "fetch"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := jc.Spawn(&core.Job{RetryPolicy: core.RetryPolicy{Jitter: "sometimes"}}); err == nil {
+		t.Error("child with an invalid retry policy accepted")
+	}
+	next := &core.Job{Type: "index", Dependencies: []string{"seed"}}
+	if err := jc.ContinueWith(next); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs := jc.spawned()
+	var ids []string
+	for _, j := range jobs {
+		ids = append(ids, j.ID)
+		if j.ParentID != "crawl" || j.Status != core.StatusPending || j.Executor != "handlers" {
+			t.Errorf("spawned job %+v", j)
+		}
+	}
+	if want := []string{"crawl.1", "custom", "crawl.3", "crawl.then"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("spawned %v, want %v", ids, want)
+	}
+	if jobs[0].Queue != "crawls" || jobs[1].Queue != "fetches" {
+		t.Errorf("queues = %s, %s", jobs[0].Queue, jobs[1].Queue)
+	}
+	cont := jobs[3]
+	if want := []string{"seed", "crawl.1", "custom", "crawl.3"}; !reflect.DeepEqual(cont.Dependencies, want) {
+		t.Errorf("continuation waits on %v, want %v", cont.Dependencies, want)
+	}
+	if !reflect.DeepEqual(jobs[0].Dependents, []string{"crawl.then"}) {
+		t.Errorf("child dependents = %v", jobs[0].Dependents)
+	}
+	if !reflect.DeepEqual(next.Dependencies, []string{"seed"}) {
+		t.Errorf("caller's job modified: %v", next.Dependencies)
+	}
+}
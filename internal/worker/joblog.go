@@ -0,0 +1,213 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/core"
+	"github.com/theb0imanuu/wida/internal/store"
+)
+
+// Job log lines are written to the store in batches of up to
+// jobLogBatchSize, at least every jobLogFlushInterval. Lines beyond
+// jobLogMaxPending waiting to be written are dropped and counted.
+const (
+	jobLogBatchSize     = 100
+	jobLogFlushInterval = time.Second
+	jobLogMaxPending    = 10 * jobLogBatchSize
+)
+
+// jobLogBuffer collects one attempt's log lines and writes them to the
+// store in the background.
+type jobLogBuffer struct {
+	ctx   context.Context
+	store store.Store
+	jobID string
+
+	mu      sync.Mutex
+	pending []core.JobLog
+	dropped int
+	closed  bool
+
+	full chan struct{}
+	quit chan struct{}
+	done chan struct{}
+}
+
+func newJobLogBuffer(ctx context.Context, s store.Store, jobID string) *jobLogBuffer {
+	b := &jobLogBuffer{
+		ctx:   ctx,
+		store: s,
+		jobID: jobID,
+		full:  make(chan struct{}, 1),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+func (b *jobLogBuffer) add(l core.JobLog) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	if len(b.pending) >= jobLogMaxPending {
+		b.dropped++
+		b.mu.Unlock()
+		return
+	}
+	b.pending = append(b.pending, l)
+	full := len(b.pending) >= jobLogBatchSize
+	b.mu.Unlock()
+	if full {
+		signal(b.full)
+	}
+}
+
+func (b *jobLogBuffer) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(jobLogFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.full:
+		case <-b.quit:
+			b.flush()
+			return
+		}
+		b.flush()
+	}
+}
+
+func (b *jobLogBuffer) flush() {
+	b.mu.Lock()
+	dropped := b.dropped
+	b.dropped = 0
+	b.mu.Unlock()
+	if dropped > 0 {
+		log.Printf("Job %s: dropped %d log lines, logged faster than they could be stored\n", b.jobID, dropped)
+	}
+
+	for {
+		b.mu.Lock()
+		n := min(len(b.pending), jobLogBatchSize)
+		batch := b.pending[:n:n]
+		b.pending = b.pending[n:]
+		b.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if err := b.store.AppendJobLogs(b.ctx, batch); err != nil {
+			log.Printf("Job %s: failed to store %d log lines: %v\n", b.jobID, n, err)
+		}
+	}
+}
+
+// close writes out the remaining lines. Lines added afterwards are dropped.
+func (b *jobLogBuffer) close() {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+	close(b.quit)
+	<-b.done
+}
+
+// signal wakes the flusher without blocking; one pending wake-up is enough.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// jobLogHandler is a slog.Handler that records to a job's log.
+type jobLogHandler struct {
+	buf     *jobLogBuffer
+	attempt int
+	attrs   []slog.Attr // already qualified by their groups
+	group   string      // prefix for attributes added later, e.g. "req."
+}
+
+func (h *jobLogHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *jobLogHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := make(map[string]any, len(h.attrs)+r.NumAttrs())
+	for _, a := range h.attrs {
+		addField(fields, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addField(fields, h.group, a)
+		return true
+	})
+
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	h.buf.add(core.JobLog{
+		JobID:   h.buf.jobID,
+		Attempt: h.attempt,
+		Level:   r.Level.String(),
+		Message: r.Message,
+		Fields:  fields,
+		Time:    t,
+	})
+	return nil
+}
+
+func (h *jobLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.group + a.Key
+		h2.attrs = append(h2.attrs, a)
+	}
+	return &h2
+}
+
+func (h *jobLogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.group = h.group + name + "."
+	return &h2
+}
+
+// addField flattens a into fields under prefix, with groups joined by dots.
+func addField(fields map[string]any, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addField(fields, prefix, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+
+	key := prefix + a.Key
+	switch v.Kind() {
+	case slog.KindDuration:
+		fields[key] = v.Duration().String()
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			fields[key] = err.Error()
+			return
+		}
+		fields[key] = v.Any()
+	default:
+		fields[key] = v.Any()
+	}
+}
@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/core"
+	"github.com/theb0imanuu/wida/internal/store"
+)
+
+// logStore records the job logs written to it. Its other methods are not
+// implemented.
+type logStore struct {
+	store.Store
+
+	mu   sync.Mutex
+	logs []core.JobLog
+}
+
+func (s *logStore) AppendJobLogs(ctx context.Context, logs []core.JobLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logs = append(s.logs, logs...)
+	return nil
+}
+
+func TestJobLogger(t *testing.T) {
+	s := &logStore{}
+	buf := newJobLogBuffer(context.Background(), s, "job-1")
+	logger := slog.New(&jobLogHandler{buf: buf, attempt: 2})
+
+	logger.With("tenant", "acme").WithGroup("req").Warn("slow upstream",
+		"took", 3*time.Second, "err", errors.New("timeout"), slog.Group("http", "status", 504))
+	for i := 0; i < jobLogBatchSize; i++ {
+		logger.Debug("tick", "i", i)
+	}
+	buf.close()
+
+	if len(s.logs) != jobLogBatchSize+1 {
+		t.Fatalf("stored %d lines, want %d", len(s.logs), jobLogBatchSize+1)
+	}
+	got := s.logs[0]
+	if got.JobID != "job-1" || got.Attempt != 2 || got.Level != "WARN" || got.Message != "slow upstream" {
+		t.Errorf("line = %+v", got)
+	}
+	want := map[string]any{
+		"tenant":          "acme",
+		"req.took":        "3s",
+		"req.err":         "timeout",
+		"req.http.status": int64(504),
+	}
+	if !reflect.DeepEqual(got.Fields, want) {
+		t.Errorf("fields = %v, want %v", got.Fields, want)
+	}
+	if last := s.logs[len(s.logs)-1]; last.Fields["i"] != int64(jobLogBatchSize-1) {
+		t.Errorf("lines out of order, last is %+v", last)
+	}
+}
+
+func TestJobLogBufferIsBounded(t *testing.T) {
+	// No flusher runs, as if the store had stalled.
+	b := &jobLogBuffer{jobID: "job-1", full: make(chan struct{}, 1)}
+	for i := 0; i < jobLogMaxPending+5; i++ {
+		b.add(core.JobLog{Message: "tick"})
+	}
+	if len(b.pending) != jobLogMaxPending || b.dropped != 5 {
+		t.Errorf("pending %d lines with %d dropped, want %d and 5", len(b.pending), b.dropped, jobLogMaxPending)
+	}
+}
+
+func TestJobLogBufferIgnoresLinesAfterClose(t *testing.T) {
+	s := &logStore{}
+	buf := newJobLogBuffer(context.Background(), s, "job-1")
+	buf.close()
+	buf.add(core.JobLog{Message: "late"})
+	if len(buf.pending) != 0 || len(s.logs) != 0 {
+		t.Errorf("line added after close was kept: pending %d, stored %d", len(buf.pending), len(s.logs))
+	}
+}
@@ -0,0 +1,110 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+// Logging logs how long each execution took and how it ended.
+func Logging() core.Middleware {
+	return func(next core.Executor) core.Executor {
+		return core.ExecutorFunc(func(ctx context.Context, job *core.Job) (json.RawMessage, error) {
+			start := time.Now()
+			result, err := next.Execute(ctx, job)
+			if err != nil {
+				log.Printf("Job %s on executor %s failed after %v: %v\n", job.ID, job.Executor, time.Since(start), err)
+			} else {
+				log.Printf("Job %s on executor %s finished in %v\n", job.ID, job.Executor, time.Since(start))
+			}
+			return result, err
+		})
+	}
+}
+
+// ExecutorMetrics are the execution counts and durations of one executor.
+// Snoozed and discarded executions are deliberate and not counted as
+// failures.
+type ExecutorMetrics struct {
+	Executions    int64         `json:"executions"`
+	Failures      int64         `json:"failures"`
+	Snoozes       int64         `json:"snoozes"`
+	Discards      int64         `json:"discards"`
+	TotalDuration time.Duration `json:"total_duration"`
+	MaxDuration   time.Duration `json:"max_duration"`
+}
+
+// Metrics collects ExecutorMetrics for every execution it wraps.
+type Metrics struct {
+	mu         sync.Mutex
+	byExecutor map[string]*ExecutorMetrics
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{byExecutor: make(map[string]*ExecutorMetrics)}
+}
+
+// Middleware records each execution under the job's executor name.
+func (m *Metrics) Middleware() core.Middleware {
+	return func(next core.Executor) core.Executor {
+		return core.ExecutorFunc(func(ctx context.Context, job *core.Job) (json.RawMessage, error) {
+			start := time.Now()
+			result, err := next.Execute(ctx, job)
+			m.record(job.Executor, time.Since(start), err)
+			return result, err
+		})
+	}
+}
+
+func (m *Metrics) record(executor string, d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	em, ok := m.byExecutor[executor]
+	if !ok {
+		em = &ExecutorMetrics{}
+		m.byExecutor[executor] = em
+	}
+	em.Executions++
+	if _, snoozed := core.SnoozeDelay(err); snoozed {
+		em.Snoozes++
+	} else if core.IsDiscard(err) {
+		em.Discards++
+	} else if err != nil {
+		em.Failures++
+	}
+	em.TotalDuration += d
+	if d > em.MaxDuration {
+		em.MaxDuration = d
+	}
+}
+
+// Snapshot returns a copy of the metrics, keyed by executor name.
+func (m *Metrics) Snapshot() map[string]ExecutorMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make(map[string]ExecutorMetrics, len(m.byExecutor))
+	for name, em := range m.byExecutor {
+		out[name] = *em
+	}
+	return out
+}
+
+// Tenant reads the tenant from the payload at path (e.g. "tenant_id") and
+// attaches it to the execution context, for core.TenantFromContext. Jobs
+// without one run with no tenant.
+func Tenant(path string) core.Middleware {
+	return func(next core.Executor) core.Executor {
+		return core.ExecutorFunc(func(ctx context.Context, job *core.Job) (json.RawMessage, error) {
+			if tenant, err := core.LookupPath(job.Payload, path); err == nil {
+				ctx = core.WithTenant(ctx, tenant)
+			}
+			return next.Execute(ctx, job)
+		})
+	}
+}
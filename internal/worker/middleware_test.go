@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+// trace records the order in which middleware runs.
+func trace(calls *[]string, name string) core.Middleware {
+	return func(next core.Executor) core.Executor {
+		return core.ExecutorFunc(func(ctx context.Context, job *core.Job) (json.RawMessage, error) {
+			*calls = append(*calls, name)
+			return next.Execute(ctx, job)
+		})
+	}
+}
+
+func TestPoolMiddlewareOrder(t *testing.T) {
+	var calls []string
+	p := NewPool("test", nil, nil)
+	p.Use(trace(&calls, "global-1"), trace(&calls, "global-2"))
+	p.UseFor("http", trace(&calls, "http"))
+	p.UseFor("subprocess", trace(&calls, "subprocess"))
+
+	inner := core.ExecutorFunc(func(ctx context.Context, job *core.Job) (json.RawMessage, error) {
+		calls = append(calls, "executor")
+		return nil, nil
+	})
+	p.wrap("http", inner).Execute(context.Background(), &core.Job{ID: "j1"})
+
+	want := []string{"global-1", "global-2", "http", "executor"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestMetricsAndTenant(t *testing.T) {
+	m := NewMetrics()
+	var tenant string
+	exec := core.Chain(core.ExecutorFunc(func(ctx context.Context, job *core.Job) (json.RawMessage, error) {
+		tenant = core.TenantFromContext(ctx)
+		switch job.ID {
+		case "bad":
+			return nil, errors.New("boom")
+		case "later":
+			return nil, core.Snooze(time.Minute)
+		case "invalid":
+			return nil, core.Discard(errors.New("no such account"))
+		}
+		return nil, nil
+	}), m.Middleware(), Tenant("account.tenant"))
+
+	exec.Execute(context.Background(), &core.Job{ID: "ok", Executor: "http", Payload: json.RawMessage(`{"account":{"tenant":"acme"}}`)})
+	if tenant != "acme" {
+		t.Errorf("tenant = %q, want acme", tenant)
+	}
+	exec.Execute(context.Background(), &core.Job{ID: "bad", Executor: "http", Payload: json.RawMessage(`{}`)})
+	if tenant != "" {
+		t.Errorf("tenant = %q for a payload without one", tenant)
+	}
+
+	exec.Execute(context.Background(), &core.Job{ID: "later", Executor: "http", Payload: json.RawMessage(`{}`)})
+	exec.Execute(context.Background(), &core.Job{ID: "invalid", Executor: "http", Payload: json.RawMessage(`{}`)})
+
+	got := m.Snapshot()["http"]
+	if got.Executions != 4 || got.Failures != 1 || got.Snoozes != 1 || got.Discards != 1 {
+		t.Errorf("metrics = %+v, want 4 executions, 1 failure, 1 snooze and 1 discard", got)
+	}
+}
@@ -2,8 +2,13 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
+	"runtime/debug"
+	"sort"
 	"sync"
 	"time"
 
@@ -11,23 +16,98 @@ import (
 	"github.com/theb0imanuu/wida/internal/store"
 )
 
+// heartbeatInterval is also how quickly a running job notices cancellation.
+const heartbeatInterval = 10 * time.Second
+
+// DefaultMaxResultSize caps the size of a stored job result.
+const DefaultMaxResultSize = 1 << 20
+
+// DefaultTimeoutGrace is how long an executor may keep running after its job
+// timed out, was cancelled or was interrupted before the pool gives up on it.
+const DefaultTimeoutGrace = 10 * time.Second
+
+// DefaultPreferenceWait is how long a job waits for a worker with its
+// preferred labels before any compatible worker may take it.
+const DefaultPreferenceWait = 30 * time.Second
+
+// DefaultMaxPanics is how many panicked attempts it takes to quarantine a job.
+const DefaultMaxPanics = 3
+
 type Pool struct {
 	ID        string
 	Store     store.Store
 	Queues    []string
-	Workers   []*core.Worker
 	Executors map[string]core.Executor // e.g., "docker", "http", "subprocess"
+
+	// MaxResultSize is the largest result, in bytes, that is persisted.
+	// Larger results are dropped and noted on the attempt.
+	MaxResultSize int
+
+	// TimeoutGrace is how long an executor that ignores its done context is
+	// waited for before being abandoned.
+	TimeoutGrace time.Duration
+
+	// MaxPanics quarantines a job in the DLQ once this many of its attempts
+	// have panicked, whatever retries it has left. Zero disables it.
+	MaxPanics int
+
+	// QueueMode decides how Queues are chosen between, with QueueWeights
+	// giving each queue's share in QueueModeWeighted.
+	QueueMode    QueueMode
+	QueueWeights map[string]int
+
+	// Labels are advertised by the pool's workers and matched against the
+	// jobs' required and preferred labels.
+	Labels         core.Labels
+	PreferenceWait time.Duration
+
+	// Autoscale, if set before Start, lets the pool vary its number of
+	// workers with the backlog. The number passed to Start is then only the
+	// initial size.
+	Autoscale *AutoscaleConfig
+
+	// Breakers, if set, stop the pool claiming from a queue or executor
+	// whose jobs keep failing. They are off by default.
+	Breakers *Breakers
+
+	middleware         []core.Middleware
+	executorMiddleware map[string][]core.Middleware
+
+	rngMu sync.Mutex
+	rng   *rand.Rand
+
+	// workers are the workers claiming jobs; live holds the IDs of every
+	// worker whose goroutine is still running, including removed ones that
+	// are draining.
+	workersMu   sync.Mutex
+	workers     []*core.Worker
+	live        map[string]bool
+	concurrency int
+
+	scaler    *autoscaler
+	stopping  chan struct{}
+	scaleDone chan struct{}
+	stopOnce  sync.Once
+
 	wg        sync.WaitGroup
-	quit      chan struct{}
+	interrupt context.CancelCauseFunc
 }
 
 func NewPool(id string, s store.Store, queues []string) *Pool {
 	return &Pool{
-		ID:        id,
-		Store:     s,
-		Queues:    queues,
-		Executors: make(map[string]core.Executor),
-		quit:      make(chan struct{}),
+		ID:                 id,
+		Store:              s,
+		Queues:             queues,
+		Executors:          make(map[string]core.Executor),
+		executorMiddleware: make(map[string][]core.Middleware),
+		MaxResultSize:      DefaultMaxResultSize,
+		TimeoutGrace:       DefaultTimeoutGrace,
+		MaxPanics:          DefaultMaxPanics,
+		QueueMode:          QueueModeFIFO,
+		PreferenceWait:     DefaultPreferenceWait,
+		rng:                rand.New(rand.NewSource(time.Now().UnixNano())),
+		live:               make(map[string]bool),
+		stopping:           make(chan struct{}),
 	}
 }
 
@@ -35,57 +115,240 @@ func (p *Pool) RegisterExecutor(name string, e core.Executor) {
 	p.Executors[name] = e
 }
 
-func (p *Pool) Start(ctx context.Context, numWorkers int) {
-	log.Printf("Starting worker pool %s with %d workers\n", p.ID, numWorkers)
+// Use wraps every executor in mws. Middleware registered with Use runs
+// outside any registered with UseFor.
+func (p *Pool) Use(mws ...core.Middleware) {
+	p.middleware = append(p.middleware, mws...)
+}
+
+// UseFor wraps the executor registered under name in mws.
+func (p *Pool) UseFor(name string, mws ...core.Middleware) {
+	p.executorMiddleware[name] = append(p.executorMiddleware[name], mws...)
+}
+
+// wrap applies the pool's middleware to the executor registered under name.
+func (p *Pool) wrap(name string, e core.Executor) core.Executor {
+	e = core.Chain(e, p.executorMiddleware[name]...)
+	return core.Chain(e, p.middleware...)
+}
+
+// executorNames lists the registered executors, in a stable order.
+func (p *Pool) executorNames() []string {
+	names := make([]string, 0, len(p.Executors))
+	for name := range p.Executors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start runs numWorkers workers, each with concurrency slots. A job takes
+// as many slots as its weight.
+func (p *Pool) Start(ctx context.Context, numWorkers, concurrency int) {
+	ctx, p.interrupt = context.WithCancelCause(ctx)
+	p.concurrency = concurrency
+	if p.Breakers != nil && p.Breakers.OnTransition == nil {
+		p.Breakers.OnTransition = p.recordBreakerEvent
+	}
+
+	if p.Autoscale != nil {
+		cfg := p.Autoscale.withDefaults()
+		numWorkers = min(max(numWorkers, cfg.Min), cfg.Max)
+		p.scaler = &autoscaler{cfg: cfg}
+		p.scaleDone = make(chan struct{})
+		log.Printf("Autoscaling worker pool %s between %d and %d workers\n", p.ID, cfg.Min, cfg.Max)
+		go p.autoscale(ctx)
+	}
+
+	log.Printf("Starting worker pool %s with %d workers of %d slots each\n", p.ID, numWorkers, concurrency)
 	for i := 0; i < numWorkers; i++ {
-		w := core.NewWorker(fmt.Sprintf("%s-%d", p.ID, i), nil, 1) // simplistic approach
-		p.Workers = append(p.Workers, w)
+		p.addWorker(ctx)
+	}
+}
 
-		p.wg.Add(1)
-		go p.runWorker(ctx, w)
+// addWorker starts a worker under the lowest ID not held by a running one.
+func (p *Pool) addWorker(ctx context.Context) {
+	p.workersMu.Lock()
+	defer p.workersMu.Unlock()
+
+	var id string
+	for i := 0; ; i++ {
+		if id = fmt.Sprintf("%s-%d", p.ID, i); !p.live[id] {
+			break
+		}
 	}
+	w := core.NewWorker(id, p.concurrency)
+	w.Fetch = p.fetch
+	w.Process = p.processJob
+	p.workers = append(p.workers, w)
+	p.live[id] = true
+
+	p.wg.Add(1)
+	go p.runWorker(ctx, w)
 }
 
-func (p *Pool) Stop() {
-	close(p.quit)
-	p.wg.Wait()
+// removeWorker stops the newest worker. It finishes the jobs it is running
+// before its goroutine exits.
+func (p *Pool) removeWorker() {
+	p.workersMu.Lock()
+	defer p.workersMu.Unlock()
+	if len(p.workers) == 0 {
+		return
+	}
+	w := p.workers[len(p.workers)-1]
+	p.workers = p.workers[:len(p.workers)-1]
+	w.Stop()
+}
+
+// activeWorkers returns the workers currently claiming jobs.
+func (p *Pool) activeWorkers() []*core.Worker {
+	p.workersMu.Lock()
+	defer p.workersMu.Unlock()
+	return append([]*core.Worker(nil), p.workers...)
+}
+
+// Stop stops claiming jobs and waits up to drainTimeout for running ones to
+// finish. Jobs still running after that are interrupted and handed back to
+// the queue, without using up a retry.
+func (p *Pool) Stop(drainTimeout time.Duration) {
+	p.stopOnce.Do(func() { close(p.stopping) })
+	if p.scaleDone != nil {
+		<-p.scaleDone
+	}
+	for _, w := range p.activeWorkers() {
+		w.Stop()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		log.Printf("Worker pool %s drained\n", p.ID)
+		return
+	case <-time.After(drainTimeout):
+	}
+
+	log.Printf("Worker pool %s not drained after %v, interrupting running jobs\n", p.ID, drainTimeout)
+	if p.interrupt != nil {
+		p.interrupt(core.ErrInterrupted)
+	}
+	<-drained
 }
 
 func (p *Pool) runWorker(ctx context.Context, w *core.Worker) {
 	defer p.wg.Done()
 
-	p.Store.RegisterWorker(context.Background(), w.ID)
+	p.Store.RegisterWorker(context.Background(), w.ID, w.Capacity(), p.Labels)
+
+	hbCtx, hbCancel := context.WithCancel(ctx)
+	go p.workerHeartbeat(hbCtx, w)
+	defer hbCancel()
+
+	w.Run(ctx)
+
+	if err := p.Store.UpdateWorkerStatus(context.Background(), w.ID, "stopped", "", 0, 0); err != nil {
+		log.Printf("Worker %s status update error: %v\n", w.ID, err)
+	}
+
+	p.workersMu.Lock()
+	delete(p.live, w.ID)
+	p.workersMu.Unlock()
+}
 
-	pollTicker := time.NewTicker(2 * time.Second)
-	defer pollTicker.Stop()
+func (p *Pool) fetch(ctx context.Context, w *core.Worker, maxWeight int) (*core.Job, int, error) {
+	queues, ordered := p.queueOrder()
+	executors := p.executorNames()
+
+	// Open breakers take their queue or executor out of the claim.
+	var probes []string
+	if p.Breakers != nil {
+		queues, executors, probes = p.Breakers.Admit(queues, executors)
+	}
+	var job *core.Job
+	var blocked int
+	var err error
+	if len(queues) > 0 && len(executors) > 0 {
+		job, blocked, err = p.Store.Dequeue(ctx, store.DequeueOptions{
+			WorkerID:   w.ID,
+			Queues:     queues,
+			QueueOrder: ordered,
+			Executors:  executors,
+
+			Labels:         p.Labels,
+			PreferenceWait: p.PreferenceWait,
+			MaxWeight:      maxWeight,
+		})
+	}
+	if p.Breakers != nil {
+		p.Breakers.Claimed(job, probes)
+	}
+	return job, blocked, err
+}
+
+// queueOrder returns the queues to claim from and whether their order is a
+// precedence.
+func (p *Pool) queueOrder() ([]string, bool) {
+	switch p.QueueMode {
+	case QueueModeStrict:
+		return p.Queues, true
+	case QueueModeWeighted:
+		p.rngMu.Lock()
+		defer p.rngMu.Unlock()
+		return weightedOrder(p.Queues, p.QueueWeights, p.rng), true
+	default:
+		return p.Queues, false
+	}
+}
+
+// workerHeartbeat keeps the worker's row fresh while it waits for jobs.
+func (p *Pool) workerHeartbeat(ctx context.Context, w *core.Worker) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-p.quit:
-			return
-		case <-pollTicker.C:
-			// Attempt to dequeue a job
-			job, err := p.Store.Dequeue(ctx, p.Queues, w.ID)
-			if err != nil {
-				log.Printf("Worker %s dequeue error: %v\n", w.ID, err)
-				continue
-			}
-			if job == nil {
-				continue // no jobs available
-			}
+		case <-ticker.C:
+			p.reportWorker(w, nil)
+		}
+	}
+}
 
-			p.processJob(ctx, w, job)
+// reportWorker records the worker's load, leaving out finished, whose
+// slots are about to be freed.
+func (p *Pool) reportWorker(w *core.Worker, finished *core.Job) {
+	var running []string
+	used := w.Used()
+	for _, id := range w.ActiveJobs() {
+		if finished != nil && id == finished.ID {
+			used -= finished.SlotWeight()
+			continue
 		}
+		running = append(running, id)
+	}
+
+	status, current := "idle", ""
+	if len(running) > 0 {
+		status, current = "running", running[0]
+	}
+	if err := p.Store.UpdateWorkerStatus(context.Background(), w.ID, status, current, len(running), used); err != nil {
+		log.Printf("Worker %s status update error: %v\n", w.ID, err)
 	}
 }
 
 func (p *Pool) processJob(ctx context.Context, w *core.Worker, job *core.Job) {
 	log.Printf("Worker %s executing job %s from queue %s\n", w.ID, job.ID, job.Queue)
 
-	p.Store.UpdateWorkerStatus(context.Background(), w.ID, "running", job.ID)
-	defer p.Store.UpdateWorkerStatus(context.Background(), w.ID, "idle", "")
+	p.reportWorker(w, nil)
+	defer p.reportWorker(w, job)
+
+	// Outcomes are recorded even if the pool is shutting down.
+	storeCtx := context.WithoutCancel(ctx)
 
 	// Create execution attempt
 	attempt := &core.Attempt{
@@ -93,52 +356,248 @@ func (p *Pool) processJob(ctx context.Context, w *core.Worker, job *core.Job) {
 		Status:    core.StatusRunning,
 	}
 
-	// Determine executor type from somewhere (assume HTTP for now as default)
-	executor := p.Executors["default"]
+	// Dequeue only hands out jobs for registered executors, so a miss here
+	// means the registry changed under us; give the job back untouched.
+	executor, ok := p.Executors[job.Executor]
+	if !ok {
+		log.Printf("Worker %s has no executor %q for job %s, releasing it\n", w.ID, job.Executor, job.ID)
+		p.Store.Release(context.Background(), job.ID, w.ID, nil)
+		p.recordOutcome(job, OutcomeNeutral)
+		return
+	}
+	executor = p.wrap(job.Executor, executor)
+
+	// The heartbeat cancels execCtx with core.ErrCancelled if the job is
+	// cancelled while it runs, and the job's deadline with core.ErrTimeout.
+	// Stop interrupts it with core.ErrInterrupted.
+	execCtx, execCancel := context.WithCancelCause(ctx)
+	defer execCancel(nil)
+	if job.Timeout > 0 {
+		var timeoutCancel context.CancelFunc
+		execCtx, timeoutCancel = context.WithTimeoutCause(execCtx, job.Timeout, core.ErrTimeout)
+		defer timeoutCancel()
+	}
+
+	// Executors report progress, save checkpoints, log and spawn child jobs
+	// through the job context. Log lines are written out in the background
+	// as they come; children wait for the job to succeed.
+	logs := newJobLogBuffer(storeCtx, p.Store, job.ID)
+	jc := newJobContext(storeCtx, p.Store, job, w.ID, logs)
+	execCtx = core.WithJobContext(execCtx, jc)
 
 	// Start heartbeat routine
 	hbCtx, hbCancel := context.WithCancel(ctx)
-	go p.heartbeat(hbCtx, job.ID, w.ID)
+	go p.heartbeat(hbCtx, job.ID, w.ID, execCancel)
 
 	// Execute job
-	execErr := executor.Execute(ctx, job)
+	result, abandoned, execErr := p.execute(execCtx, executor, job, attempt)
 
 	hbCancel()
 
+	// Write out the job's log before its outcome, so whoever sees it finish
+	// can read the whole log.
+	logs.close()
+
+	if p.scaler != nil {
+		p.scaler.observe(time.Since(attempt.StartedAt))
+	}
+
+	if abandoned {
+		// The executor goroutine may still touch the attempt it was given.
+		attempt = &core.Attempt{StartedAt: attempt.StartedAt}
+	}
 	attempt.FinishedAt = time.Now()
-	if execErr != nil {
-		attempt.Status = core.StatusFailed
+
+	cause := context.Cause(execCtx)
+	snooze, snoozed := core.SnoozeDelay(execErr)
+	outcome := OutcomeNeutral
+	switch {
+	case execErr != nil && errors.Is(cause, core.ErrCancelled):
+		attempt.Status = core.StatusCancelled
+		attempt.Error = execErr.Error()
+		job.Status = core.StatusCancelled
+		log.Printf("Job %s cancelled on worker %s\n", job.ID, w.ID)
+		p.Store.UpdateStatus(storeCtx, job.ID, w.ID, core.StatusCancelled, attempt)
+
+	case execErr != nil && errors.Is(cause, core.ErrTimeout):
+		attempt.Status = core.StatusTimeout
 		attempt.Error = execErr.Error()
-		log.Printf("Job %s failed on worker %s: %v\n", job.ID, w.ID, execErr)
+		log.Printf("Job %s timed out on worker %s after %v\n", job.ID, w.ID, job.Timeout)
+		outcome = OutcomeFailure
+		p.retryOrBury(storeCtx, job, w.ID, attempt, execErr)
 
-		job.Status = core.StatusFailed
-		job.Attempts = append(job.Attempts, *attempt)
+	case execErr != nil && errors.Is(cause, core.ErrInterrupted):
+		attempt.Status = core.StatusInterrupted
+		attempt.Error = execErr.Error()
+		job.Status = core.StatusPending
+		log.Printf("Job %s interrupted on worker %s, handing it back\n", job.ID, w.ID)
+		p.Store.Release(storeCtx, job.ID, w.ID, attempt)
 
-		// Check Retry Policy
-		if len(job.Attempts) >= job.MaxRetries {
-			log.Printf("Job %s max retries reached. Moving to DLQ.\n", job.ID)
-			p.Store.MoveToDLQ(ctx, job.ID, execErr.Error())
+	case snoozed:
+		// Not a failure: no attempt is recorded, so no retry is used up.
+		job.Status = core.StatusPending
+		log.Printf("Job %s snoozed on worker %s for %v\n", job.ID, w.ID, snooze)
+		p.Store.Retry(storeCtx, job.ID, w.ID, nil, time.Now().Add(snooze))
+
+	case execErr != nil && core.IsDiscard(execErr):
+		attempt.Status = core.StatusFailed
+		attempt.Error = execErr.Error()
+		job.Status = core.StatusDiscarded
+		log.Printf("Job %s discarded on worker %s: %v\n", job.ID, w.ID, execErr)
+		p.Store.UpdateStatus(storeCtx, job.ID, w.ID, core.StatusDiscarded, attempt)
+
+	case execErr != nil:
+		attempt.Status = core.StatusFailed
+		attempt.Error = execErr.Error()
+		var panicErr *core.PanicError
+		if errors.As(execErr, &panicErr) {
+			attempt.Stack = string(panicErr.Stack)
+			log.Printf("Job %s panicked on worker %s: %v\n%s\n", job.ID, w.ID, panicErr.Value, panicErr.Stack)
 		} else {
-			// Schedule next retry
-			job.Status = core.StatusPending
-			delay := core.CalculateRetryDelay(len(job.Attempts), job.RetryPolicy)
-			nextRun := time.Now().Add(delay)
-			job.RunAt = &nextRun
-			p.Store.UpdateStatus(ctx, job.ID, core.StatusPending, attempt) // We might need a separate rescheduled method or just update status with run_at
-			// TODO: Add proper reschedule logic in store to update run_at
+			log.Printf("Job %s failed on worker %s: %v\n", job.ID, w.ID, execErr)
+			if !core.IsPermanent(execErr) {
+				outcome = OutcomeFailure
+			}
 		}
-	} else {
+		p.retryOrBury(storeCtx, job, w.ID, attempt, execErr)
+
+	default:
 		attempt.Status = core.StatusSuccess
 		job.Status = core.StatusSuccess
+		job.Result = p.storableResult(job.ID, result, attempt)
+		outcome = OutcomeSuccess
+		spawned := jc.spawned()
+		err := p.Store.Complete(storeCtx, job, w.ID, attempt, spawned)
+		if errors.Is(err, store.ErrJobLost) {
+			log.Printf("Job %s finished on worker %s after it was taken over, dropping the result\n", job.ID, w.ID)
+			break
+		}
+		if err != nil && len(spawned) > 0 {
+			// Most likely a child's ID is already taken. Fail the attempt
+			// rather than leave the job running with nothing to finish it.
+			attempt.Status = core.StatusFailed
+			attempt.Error = fmt.Sprintf("enqueueing %d spawned jobs: %v", len(spawned), err)
+			job.Result = nil
+			log.Printf("Job %s on worker %s could not enqueue its spawned jobs: %v\n", job.ID, w.ID, err)
+			outcome = OutcomeFailure
+			p.retryOrBury(storeCtx, job, w.ID, attempt, errors.New(attempt.Error))
+			break
+		}
 		log.Printf("Job %s succeeded on worker %s\n", job.ID, w.ID)
-		p.Store.UpdateStatus(ctx, job.ID, core.StatusSuccess, attempt)
 	}
+	p.recordOutcome(job, outcome)
 
 	p.Store.IncrementWorkerJobs(context.Background(), w.ID)
 }
 
-func (p *Pool) heartbeat(ctx context.Context, jobID, workerID string) {
-	ticker := time.NewTicker(30 * time.Second)
+// execute runs the executor, giving it TimeoutGrace to return once its
+// context is done, whether by deadline, cancellation or shutdown. An executor
+// that ignores its context is abandoned after that, so a hung job cannot hold
+// the worker forever. A panic in the executor is
+// recovered and returned as a *core.PanicError.
+func (p *Pool) execute(ctx context.Context, executor core.Executor, job *core.Job, attempt *core.Attempt) (result json.RawMessage, abandoned bool, err error) {
+	type outcome struct {
+		result json.RawMessage
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				done <- outcome{err: &core.PanicError{Value: v, Stack: debug.Stack()}}
+			}
+		}()
+		result, err := executor.Execute(core.WithAttempt(ctx, attempt), job)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.result, false, o.err
+	case <-ctx.Done():
+	}
+
+	grace := time.NewTimer(p.TimeoutGrace)
+	defer grace.Stop()
+
+	cause := context.Cause(ctx)
+	select {
+	case o := <-done:
+		if o.err == nil && errors.Is(cause, core.ErrTimeout) {
+			// Finished, but only after its deadline: the work is done, so
+			// keep the result.
+			log.Printf("Job %s finished after its %v timeout\n", job.ID, job.Timeout)
+		}
+		return o.result, false, o.err
+	case <-grace.C:
+		log.Printf("Job %s ignored its context (%v), abandoning it\n", job.ID, cause)
+		return nil, true, fmt.Errorf("%w: executor did not stop within %v", cause, p.TimeoutGrace)
+	}
+}
+
+// retryOrBury schedules the next attempt of a failed job, or moves it to the
+// DLQ once it failed permanently or ran out of retries.
+func (p *Pool) retryOrBury(ctx context.Context, job *core.Job, workerID string, attempt *core.Attempt, execErr error) {
+	job.Status = core.StatusFailed
+	job.Attempts = append(job.Attempts, *attempt)
+
+	// A job that keeps crashing its executor is a poison pill: stop feeding
+	// it to workers.
+	if panics := countPanics(job.Attempts); p.MaxPanics > 0 && panics >= p.MaxPanics {
+		log.Printf("Job %s panicked %d times. Quarantining in DLQ.\n", job.ID, panics)
+		p.Store.MoveToDLQ(ctx, job.ID, workerID, attempt, fmt.Sprintf("quarantined after %d panics: %v", panics, execErr))
+		return
+	}
+
+	// Check Retry Policy
+	nextRun, ok := core.NextRetry(job, execErr, time.Now())
+	if !ok {
+		if core.IsPermanent(execErr) {
+			log.Printf("Job %s failed permanently. Moving to DLQ.\n", job.ID)
+		} else {
+			log.Printf("Job %s max retries reached. Moving to DLQ.\n", job.ID)
+		}
+		// The final attempt travels to the DLQ with the rest.
+		p.Store.MoveToDLQ(ctx, job.ID, workerID, attempt, execErr.Error())
+		return
+	}
+
+	// Schedule next retry
+	job.Status = core.StatusPending
+	job.RunAt = &nextRun
+	p.Store.Retry(ctx, job.ID, workerID, attempt, nextRun)
+}
+
+func countPanics(attempts []core.Attempt) int {
+	n := 0
+	for _, a := range attempts {
+		if a.Stack != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// storableResult validates an executor's result before it is persisted. A
+// result that is not JSON is kept as a JSON string; one over MaxResultSize is
+// dropped, and the attempt says so.
+func (p *Pool) storableResult(jobID string, result json.RawMessage, attempt *core.Attempt) json.RawMessage {
+	if len(result) == 0 {
+		return nil
+	}
+	if !json.Valid(result) {
+		result, _ = json.Marshal(string(result))
+	}
+	if p.MaxResultSize > 0 && len(result) > p.MaxResultSize {
+		log.Printf("Job %s result of %d bytes exceeds the %d byte limit, discarding\n", jobID, len(result), p.MaxResultSize)
+		attempt.Error = fmt.Sprintf("result discarded: %d bytes exceeds the %d byte limit", len(result), p.MaxResultSize)
+		return nil
+	}
+	return result
+}
+
+func (p *Pool) heartbeat(ctx context.Context, jobID, workerID string, cancelJob context.CancelCauseFunc) {
+	ticker := time.NewTicker(heartbeatInterval)
 	defer ticker.Stop()
 
 	for {
@@ -146,7 +605,39 @@ func (p *Pool) heartbeat(ctx context.Context, jobID, workerID string) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			p.Store.Heartbeat(context.Background(), jobID, workerID)
+			cancelRequested, err := p.Store.Heartbeat(context.Background(), jobID, workerID)
+			if err != nil {
+				log.Printf("Worker %s heartbeat error for job %s: %v\n", workerID, jobID, err)
+				continue
+			}
+			if cancelRequested {
+				log.Printf("Job %s cancellation requested, stopping execution\n", jobID)
+				cancelJob(core.ErrCancelled)
+				return
+			}
 		}
 	}
 }
+
+// recordOutcome counts the execution towards the job's breakers.
+func (p *Pool) recordOutcome(job *core.Job, outcome Outcome) {
+	if p.Breakers != nil {
+		p.Breakers.Record(job, outcome)
+	}
+}
+
+// recordBreakerEvent logs a breaker changing state and keeps it as an event.
+func (p *Pool) recordBreakerEvent(t BreakerTransition) {
+	log.Printf("Worker pool %s breaker %s %s -> %s: %s\n", p.ID, t.Key, t.From, t.To, t.Reason)
+	data, _ := json.Marshal(map[string]BreakerState{"from": t.From, "to": t.To})
+	event := &core.Event{
+		Type:    core.EventBreaker,
+		Subject: t.Key,
+		Message: t.Reason,
+		Node:    p.ID,
+		Data:    data,
+	}
+	if err := p.Store.RecordEvent(context.Background(), event); err != nil {
+		log.Printf("Worker pool %s failed to record breaker event: %v\n", p.ID, err)
+	}
+}
@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/theb0imanuu/wida/internal/core"
+)
+
+func TestExecuteRecoversPanic(t *testing.T) {
+	p := NewPool("test", nil, nil)
+	boom := core.ExecutorFunc(func(ctx context.Context, job *core.Job) (json.RawMessage, error) {
+		panic("boom")
+	})
+
+	_, abandoned, err := p.execute(context.Background(), boom, &core.Job{ID: "j1"}, &core.Attempt{})
+	if abandoned {
+		t.Fatal("panicking executor reported as abandoned")
+	}
+	var panicErr *core.PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("err = %v, want *core.PanicError", err)
+	}
+	if panicErr.Value != "boom" || err.Error() != "panic: boom" {
+		t.Errorf("panic value = %v, error = %q", panicErr.Value, err)
+	}
+	if !strings.Contains(string(panicErr.Stack), "TestExecuteRecoversPanic") {
+		t.Errorf("stack does not reach the executor:\n%s", panicErr.Stack)
+	}
+}
+
+func TestCountPanics(t *testing.T) {
+	attempts := []core.Attempt{
+		{Status: core.StatusFailed, Stack: "goroutine 1"},
+		{Status: core.StatusFailed, Error: "plain failure"},
+		{Status: core.StatusFailed, Stack: "goroutine 7"},
+	}
+	if n := countPanics(attempts); n != 2 {
+		t.Errorf("countPanics = %d, want 2", n)
+	}
+}
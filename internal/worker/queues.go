@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// QueueMode is how a pool chooses between its queues.
+type QueueMode string
+
+const (
+	// QueueModeFIFO claims the oldest ready job across all queues.
+	QueueModeFIFO QueueMode = "fifo"
+	// QueueModeStrict drains queues in the order listed: a job is only
+	// claimed from a queue when every earlier queue has none ready.
+	QueueModeStrict QueueMode = "strict"
+	// QueueModeWeighted draws a fresh queue order for every claim, each
+	// queue coming first in proportion to its weight. Every queue with a
+	// positive weight gets a share, so none starves.
+	QueueModeWeighted QueueMode = "weighted"
+)
+
+// ParseQueues reads a queue list such as "high:6,default:3,low:1". Queues
+// without a weight get 1.
+func ParseQueues(spec string) ([]string, map[string]int, error) {
+	var queues []string
+	weights := make(map[string]int)
+	for _, part := range strings.Split(spec, ",") {
+		name, weight, hasWeight := strings.Cut(strings.TrimSpace(part), ":")
+		if name == "" {
+			continue
+		}
+		w := 1
+		if hasWeight {
+			var err error
+			if w, err = strconv.Atoi(weight); err != nil || w < 0 {
+				return nil, nil, fmt.Errorf("invalid weight %q for queue %s", weight, name)
+			}
+		}
+		if _, dup := weights[name]; !dup {
+			queues = append(queues, name)
+		}
+		weights[name] = w
+	}
+	return queues, weights, nil
+}
+
+// weightedOrder returns queues in a random order where each queue's chance
+// of coming first is proportional to its weight (Efraimidis-Spirakis
+// sampling). Queues missing from weights count as weight 1; a zero weight
+// puts a queue last, so it only runs when the others have nothing ready.
+func weightedOrder(queues []string, weights map[string]int, rng *rand.Rand) []string {
+	keys := make(map[string]float64, len(queues))
+	for _, q := range queues {
+		w, ok := weights[q]
+		if !ok {
+			w = 1
+		}
+		if w <= 0 {
+			keys[q] = -1
+			continue
+		}
+		keys[q] = math.Pow(rng.Float64(), 1/float64(w))
+	}
+
+	order := append([]string(nil), queues...)
+	sort.SliceStable(order, func(i, j int) bool { return keys[order[i]] > keys[order[j]] })
+	return order
+}
@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestParseQueues(t *testing.T) {
+	queues, weights, err := ParseQueues("high:6, default:3,low")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"high", "default", "low"}; !reflect.DeepEqual(queues, want) {
+		t.Errorf("queues = %v, want %v", queues, want)
+	}
+	if want := map[string]int{"high": 6, "default": 3, "low": 1}; !reflect.DeepEqual(weights, want) {
+		t.Errorf("weights = %v, want %v", weights, want)
+	}
+
+	if _, _, err := ParseQueues("high:lots"); err == nil {
+		t.Error("non-numeric weight accepted")
+	}
+}
+
+func TestWeightedOrder(t *testing.T) {
+	queues := []string{"high", "default", "low", "paused"}
+	weights := map[string]int{"high": 6, "default": 3, "low": 1, "paused": 0}
+	rng := rand.New(rand.NewSource(1))
+
+	const draws = 10000
+	first := make(map[string]int)
+	for i := 0; i < draws; i++ {
+		order := weightedOrder(queues, weights, rng)
+		if len(order) != len(queues) {
+			t.Fatalf("order %v lost queues", order)
+		}
+		if order[len(order)-1] != "paused" {
+			t.Fatalf("zero-weight queue not last in %v", order)
+		}
+		first[order[0]]++
+	}
+
+	// Expected shares 60%, 30% and 10%; low must not starve.
+	for q, want := range map[string]float64{"high": 0.6, "default": 0.3, "low": 0.1} {
+		got := float64(first[q]) / draws
+		if got < want-0.03 || got > want+0.03 {
+			t.Errorf("%s came first %.1f%% of the time, want about %.0f%%", q, got*100, want*100)
+		}
+	}
+}
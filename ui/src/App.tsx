@@ -1,5 +1,5 @@
 import { useState, useEffect, useCallback } from 'react';
-import type { GlobalStats, Job, WorkerStats, DLQJob } from './types';
+import type { GlobalStats, Job, WorkerStats, DLQJob, QueueConfig, ScalingStatus } from './types';
 import { Layout } from './layouts/AppLayout';
 import { Dashboard } from './pages/Dashboard';
 import { Queues } from './pages/Queues';
@@ -13,26 +13,31 @@ export default function App() {
   const [activeTab, setActiveTab] = useState('Dashboard');
   const [jobs, setJobs] = useState<Job[]>([]);
   const [workers, setWorkers] = useState<WorkerStats[]>([]);
+  const [scaling, setScaling] = useState<ScalingStatus | null>(null);
   const [dlq, setDlq] = useState<DLQJob[]>([]);
+  const [queueConfigs, setQueueConfigs] = useState<QueueConfig[]>([]);
   const [isLeader, setIsLeader] = useState<boolean>(false);
   
   const fetchData = useCallback(async () => {
     try {
-      const [resJobs, resWorkers, resDlq, resSched] = await Promise.all([
+      const [resJobs, resWorkers, resDlq, resSched, resQueues] = await Promise.all([
         fetch('/api/jobs'),
         fetch('/api/workers'),
         fetch('/api/dlq'),
-        fetch('/api/scheduler')
+        fetch('/api/scheduler'),
+        fetch('/api/queues')
       ]);
       
-      const [dataJobs, dataWorkers, dataDlq, dataSched] = await Promise.all([
-        resJobs.json(), resWorkers.json(), resDlq.json(), resSched.json()
+      const [dataJobs, dataWorkers, dataDlq, dataSched, dataQueues] = await Promise.all([
+        resJobs.json(), resWorkers.json(), resDlq.json(), resSched.json(), resQueues.json()
       ]);
 
       if (dataJobs.jobs) setJobs(dataJobs.jobs);
       if (dataWorkers.workers) setWorkers(dataWorkers.workers);
+      setScaling(dataWorkers.scaling ?? null);
       if (dataDlq.dlq) setDlq(dataDlq.dlq);
       if (dataSched.is_leader !== undefined) setIsLeader(dataSched.is_leader);
+      if (dataQueues.queues) setQueueConfigs(dataQueues.queues);
 
     } catch (err) {
       console.error('Failed to fetch data', err);
@@ -60,6 +65,22 @@ export default function App() {
     }
   };
 
+  const handleCancel = async (jobId: string): Promise<Job | null> => {
+    try {
+      const res = await fetch(`/api/jobs/${encodeURIComponent(jobId)}/cancel`, { method: 'POST' });
+      if (!res.ok) {
+        alert(`Failed to cancel job: ${await res.text()}`);
+        return null;
+      }
+      fetchData();
+      return await res.json();
+    } catch (err) {
+      console.error(err);
+      alert('Failed to cancel job');
+      return null;
+    }
+  };
+
   const totalJobs = jobs.length;
   const runningJobs = jobs.filter(j => j.status === 'running').length;
   const failedJobs = jobs.filter(j => j.status === 'failed').length;
@@ -78,13 +99,13 @@ export default function App() {
         <Dashboard stats={globalStats} jobs={jobs} workers={workers} dlq={dlq} isLeader={isLeader} />
       )}
       {activeTab === 'Queues' && (
-        <Queues jobs={jobs} onEnqueueJob={handleEnqueue} />
+        <Queues jobs={jobs} queueConfigs={queueConfigs} onEnqueueJob={handleEnqueue} />
       )}
       {activeTab === 'Jobs' && (
-        <Jobs jobs={jobs} />
+        <Jobs jobs={jobs} onCancelJob={handleCancel} />
       )}
       {activeTab === 'Workers' && (
-        <Workers workers={workers} />
+        <Workers workers={workers} scaling={scaling} />
       )}
       {activeTab === 'Scheduler' && (
         <Scheduler jobs={jobs} isLeader={isLeader} />
@@ -1,6 +1,6 @@
 import React from 'react';
 
-type BadgeVariant = 'pending' | 'running' | 'success' | 'failed' | 'dead';
+type BadgeVariant = 'pending' | 'running' | 'success' | 'failed' | 'dead' | 'cancelled' | 'discarded' | 'timeout' | 'interrupted' | 'stopped';
 
 interface BadgeProps extends React.HTMLAttributes<HTMLSpanElement> {
   variant: BadgeVariant;
@@ -13,6 +13,11 @@ const variantStyles: Record<BadgeVariant, string> = {
   success: 'bg-status-success/10 text-success border border-status-success/20',
   failed: 'bg-status-failed/10 text-failed border border-status-failed/20',
   dead: 'bg-status-dead/10 text-dead border border-status-dead/20',
+  cancelled: 'bg-white/5 text-secondary border border-border',
+  discarded: 'bg-white/5 text-secondary border border-border',
+  timeout: 'bg-status-failed/10 text-failed border border-status-failed/20',
+  interrupted: 'bg-status-pending/10 text-pending border border-status-pending/20',
+  stopped: 'bg-white/5 text-secondary border border-border',
 };
 
 export function Badge({ variant, children, className = '', ...props }: BadgeProps) {
@@ -1,5 +1,5 @@
-import React, { useState } from 'react';
-import type { Job } from '../types';
+import React, { useState, useEffect } from 'react';
+import type { Job, JobLog, JobTree } from '../types';
 import { Badge } from '../components/ui/Badge';
 import { Card } from '../components/ui/Card';
 import { Table, TableHeader, TableRow, TableHead, TableCell } from '../components/ui/Table';
@@ -8,13 +8,90 @@ import { PlayCircle, Clock, CheckCircle2, XCircle, ChevronRight, X } from 'lucid
 
 interface JobsProps {
   jobs: Job[];
+  onCancelJob: (jobId: string) => Promise<Job | null>;
 }
 
-type BadgeVariant = 'pending' | 'running' | 'success' | 'failed' | 'dead';
+type BadgeVariant = 'pending' | 'running' | 'success' | 'failed' | 'dead' | 'cancelled' | 'discarded' | 'timeout' | 'interrupted';
 
-export const Jobs: React.FC<JobsProps> = ({ jobs }) => {
+export const Jobs: React.FC<JobsProps> = ({ jobs, onCancelJob }) => {
   const [selectedJob, setSelectedJob] = useState<Job | null>(null);
 
+  // The list endpoint omits results, so stream the full job while one is
+  // open; the server pushes it again on every status or progress change.
+  const selectedId = selectedJob?.id;
+  useEffect(() => {
+    if (!selectedId) return;
+    const source = new EventSource(`/api/jobs/${encodeURIComponent(selectedId)}/stream`);
+    source.addEventListener('job', (e) => setSelectedJob(JSON.parse((e as MessageEvent).data) as Job));
+    source.addEventListener('removed', () => source.close());
+    return () => source.close();
+  }, [selectedId]);
+
+  // Poll the open job's log for lines after the last one seen.
+  const [logs, setLogs] = useState<{ jobId: string; lines: JobLog[] }>({ jobId: '', lines: [] });
+  useEffect(() => {
+    if (!selectedId) return;
+    let after = 0;
+    let cancelled = false;
+    const load = () => {
+      fetch(`/api/jobs/${encodeURIComponent(selectedId)}/logs?after=${after}`)
+        .then(res => res.ok ? res.json() : null)
+        .then((data: { logs: JobLog[] } | null) => {
+          if (cancelled || !data) return;
+          const lines = data.logs;
+          if (lines.length > 0) after = lines[lines.length - 1].id;
+          setLogs(prev => ({ jobId: selectedId, lines: prev.jobId === selectedId ? [...prev.lines, ...lines] : lines }));
+        })
+        .catch(console.error);
+    };
+    load();
+    const interval = setInterval(load, 2000);
+    return () => { cancelled = true; clearInterval(interval); };
+  }, [selectedId]);
+  const logLines = logs.jobId === selectedId ? logs.lines : [];
+
+  // Children are only enqueued once the job succeeds, so reload the tree
+  // whenever its status changes.
+  const selectedStatus = selectedJob?.status;
+  const [tree, setTree] = useState<JobTree | null>(null);
+  useEffect(() => {
+    if (!selectedId) return;
+    let cancelled = false;
+    fetch(`/api/jobs/${encodeURIComponent(selectedId)}/tree`)
+      .then(res => res.ok ? res.json() : null)
+      .then((data: { tree: JobTree } | null) => { if (!cancelled) setTree(data ? data.tree : null); })
+      .catch(console.error);
+    return () => { cancelled = true; };
+  }, [selectedId, selectedStatus]);
+  const children = tree && tree.id === selectedId ? tree.children ?? [] : [];
+
+  const openJob = (id: string) => {
+    fetch(`/api/jobs/${encodeURIComponent(id)}`)
+      .then(res => res.ok ? res.json() : null)
+      .then((job: Job | null) => { if (job) setSelectedJob(job); })
+      .catch(console.error);
+  };
+
+  const renderTree = (nodes: JobTree[], depth: number): React.ReactNode[] =>
+    nodes.flatMap((node) => [
+      <button
+        key={node.id}
+        onClick={() => openJob(node.id)}
+        className="w-full flex items-center justify-between px-3 py-2 text-left hover:bg-white/5 rounded transition-colors"
+        style={{ paddingLeft: `${12 + depth * 16}px` }}
+      >
+        <span className="font-mono text-xs text-primary truncate">{node.id}{node.type && <span className="text-secondary"> {node.type}</span>}</span>
+        <Badge variant={node.status as BadgeVariant}>{node.status}</Badge>
+      </button>,
+      ...renderTree(node.children ?? [], depth + 1),
+    ]);
+
+  const cancelSelected = async () => {
+    if (!selectedJob) return;
+    const updated = await onCancelJob(selectedJob.id);
+    if (updated) setSelectedJob(updated);
+  };
+
   return (
     <div className="relative animate-in fade-in slide-in-from-bottom-2 duration-300">
       <Card className="p-0 overflow-hidden">
@@ -47,6 +124,9 @@ export const Jobs: React.FC<JobsProps> = ({ jobs }) => {
                   </TableCell>
                   <TableCell>
                     <Badge variant={job.status as BadgeVariant}>{job.status}</Badge>
+                    {job.status === 'running' && job.progress && (
+                      <span className="ml-2 text-xs text-secondary font-mono">{job.progress.percent.toFixed(0)}%</span>
+                    )}
                   </TableCell>
                   <TableCell className="text-secondary text-sm">
                     {job.attempts ? job.attempts.length : 0} <span className="opacity-40">/ {job.max_retries}</span>
@@ -96,8 +176,58 @@ export const Jobs: React.FC<JobsProps> = ({ jobs }) => {
                   <span className="block text-[10px] text-secondary uppercase tracking-widest mb-1.5 font-semibold">Timeout</span>
                   <span className="font-medium text-primary text-sm">{selectedJob.timeout} ns</span>
                 </div>
+                <div>
+                  <span className="block text-[10px] text-secondary uppercase tracking-widest mb-1.5 font-semibold">Executor</span>
+                  <span className="font-medium text-primary text-sm font-mono">{selectedJob.executor || 'default'}</span>
+                </div>
+                {selectedJob.parent_id && (
+                  <div>
+                    <span className="block text-[10px] text-secondary uppercase tracking-widest mb-1.5 font-semibold">Parent</span>
+                    <button onClick={() => openJob(selectedJob.parent_id!)} className="font-medium text-primary text-sm font-mono hover:underline">{selectedJob.parent_id}</button>
+                  </div>
+                )}
+                {(selectedJob.weight ?? 1) > 1 && (
+                  <div>
+                    <span className="block text-[10px] text-secondary uppercase tracking-widest mb-1.5 font-semibold">Weight</span>
+                    <span className="font-medium text-primary text-sm">{selectedJob.weight} slots</span>
+                  </div>
+                )}
+                {selectedJob.required_labels && (
+                  <div>
+                    <span className="block text-[10px] text-secondary uppercase tracking-widest mb-1.5 font-semibold">Requires</span>
+                    <span className="font-medium text-primary text-sm font-mono">{Object.entries(selectedJob.required_labels).map(([k, v]) => `${k}=${v}`).join(', ')}</span>
+                  </div>
+                )}
+                {selectedJob.preferred_labels && (
+                  <div>
+                    <span className="block text-[10px] text-secondary uppercase tracking-widest mb-1.5 font-semibold">Prefers</span>
+                    <span className="font-medium text-primary text-sm font-mono">{Object.entries(selectedJob.preferred_labels).map(([k, v]) => `${k}=${v}`).join(', ')}</span>
+                  </div>
+                )}
               </div>
 
+              {selectedJob.progress && (
+                <div className="p-4 rounded-lg border border-border bg-card">
+                  <div className="flex justify-between items-center mb-2 text-xs">
+                    <span className="text-[10px] font-semibold text-secondary uppercase tracking-widest">Progress</span>
+                    <span className="font-mono text-primary">{selectedJob.progress.percent.toFixed(1)}%</span>
+                  </div>
+                  <div className="h-1.5 rounded bg-white/5 overflow-hidden">
+                    <div className="h-full bg-status-running transition-all" style={{ width: `${selectedJob.progress.percent}%` }} />
+                  </div>
+                  <div className="flex justify-between mt-2 text-xs text-secondary">
+                    <span>{selectedJob.progress.message}</span>
+                    <span>{new Date(selectedJob.progress.updated_at).toLocaleTimeString()}</span>
+                  </div>
+                </div>
+              )}
+
+              {selectedJob.unplaced_reason && (
+                <div className="p-4 rounded-lg border border-status-pending/20 bg-status-pending/10 text-pending text-sm">
+                  <span className="font-semibold">Unplaced:</span> {selectedJob.unplaced_reason}
+                </div>
+              )}
+
               <div>
                 <div className="flex justify-between items-center mb-3">
                   <h4 className="text-[10px] font-semibold text-secondary uppercase tracking-widest">Payload Data</h4>
@@ -109,6 +239,49 @@ export const Jobs: React.FC<JobsProps> = ({ jobs }) => {
                 </div>
               </div>
 
+              {selectedJob.result !== undefined && (
+                <div>
+                  <div className="flex justify-between items-center mb-3">
+                    <h4 className="text-[10px] font-semibold text-secondary uppercase tracking-widest">Result</h4>
+                    {selectedJob.result_expires_at && (
+                      <span className="text-[10px] text-secondary">Expires {new Date(selectedJob.result_expires_at).toLocaleString()}</span>
+                    )}
+                  </div>
+                  <div className="bg-[#0B0F14] rounded-lg p-5 overflow-x-auto border border-border">
+                    <pre className="text-[#E6EDF3] font-mono text-xs leading-relaxed">
+                      {JSON.stringify(selectedJob.result, null, 2)}
+                    </pre>
+                  </div>
+                </div>
+              )}
+
+              {children.length > 0 && (
+                <div>
+                  <h4 className="text-[10px] font-semibold text-secondary uppercase tracking-widest mb-3">Spawned Jobs</h4>
+                  <div className="bg-card rounded-lg border border-border py-1 max-h-72 overflow-auto">
+                    {renderTree(children, 0)}
+                  </div>
+                </div>
+              )}
+
+              {logLines.length > 0 && (
+                <div>
+                  <h4 className="text-[10px] font-semibold text-secondary uppercase tracking-widest mb-3">Logs</h4>
+                  <div className="bg-[#0B0F14] rounded-lg p-4 max-h-72 overflow-auto border border-border font-mono text-[11px] leading-relaxed">
+                    {logLines.map((l) => (
+                      <div key={l.id} className="whitespace-pre-wrap break-all">
+                        <span className="text-secondary">{new Date(l.time).toLocaleTimeString()} #{l.attempt} </span>
+                        <span className={l.level === 'ERROR' ? 'text-status-dead' : l.level === 'WARN' ? 'text-pending' : 'text-secondary'}>{l.level.padEnd(5)} </span>
+                        <span className="text-[#E6EDF3]">{l.message}</span>
+                        {l.fields && Object.entries(l.fields).map(([k, v]) => (
+                          <span key={k} className="text-secondary"> {k}={typeof v === 'string' ? v : JSON.stringify(v)}</span>
+                        ))}
+                      </div>
+                    ))}
+                  </div>
+                </div>
+              )}
+
               {selectedJob.attempts && selectedJob.attempts.length > 0 && (
                 <div>
                   <h4 className="text-[10px] font-semibold text-secondary uppercase tracking-widest mb-3">Execution History</h4>
@@ -132,12 +305,20 @@ export const Jobs: React.FC<JobsProps> = ({ jobs }) => {
                               <Clock className="w-3.5 h-3.5 opacity-50" /> Finished: {new Date(attempt.finished_at).toLocaleTimeString()}
                             </div>
                           )}
+                          {attempt.exit_code !== undefined && (
+                            <div className="font-mono">Exit code: {attempt.exit_code}</div>
+                          )}
                         </div>
                         {attempt.error && (
                           <div className="mt-3 ml-2 text-status-dead bg-status-dead/10 p-3 rounded border border-status-dead/20 font-mono text-[11px] break-all">
                             {attempt.error}
                           </div>
                         )}
+                        {attempt.stack && (
+                          <pre className="mt-2 ml-2 max-h-48 overflow-auto text-secondary bg-black/20 p-3 rounded border border-white/5 font-mono text-[10px] whitespace-pre">
+                            {attempt.stack}
+                          </pre>
+                        )}
                       </div>
                     ))}
                   </div>
@@ -151,6 +332,11 @@ export const Jobs: React.FC<JobsProps> = ({ jobs }) => {
                    Retry Job Manually
                  </Button>
                )}
+               {['pending', 'failed', 'running'].includes(selectedJob.status) && (
+                 <Button variant="secondary" onClick={cancelSelected} disabled={selectedJob.cancel_requested}>
+                   {selectedJob.cancel_requested ? 'Cancelling...' : 'Cancel Job'}
+                 </Button>
+               )}
                <Button variant="danger">
                  Move to DLQ
                </Button>
@@ -1,23 +1,23 @@
 import React, { useState } from 'react';
-import type { Job } from '../types';
+import type { Job, QueueConfig } from '../types';
 import { PlusCircle, ListTree } from 'lucide-react';
 import { Card } from '../components/ui/Card';
 import { Button } from '../components/ui/Button';
 
 interface QueuesProps {
   jobs: Job[];
+  queueConfigs: QueueConfig[];
   onEnqueueJob: (jobData: Partial<Job>) => Promise<void>;
 }
 
-export const Queues: React.FC<QueuesProps> = ({ jobs, onEnqueueJob }) => {
+export const Queues: React.FC<QueuesProps> = ({ jobs, queueConfigs, onEnqueueJob }) => {
   const [showModal, setShowModal] = useState(false);
   const [formData, setFormData] = useState(() => ({
     name: `job-ui-${Date.now()}`,
     queue: 'default',
     payload: '{\n  "message": "Hello Wida from UI!"\n}',
     cron_expr: '',
-    timeout: 30000,
-    max_retries: 3,
+    timeout: '',
     dependencies: ''
   }));
   const [loading, setLoading] = useState(false);
@@ -33,9 +33,16 @@ export const Queues: React.FC<QueuesProps> = ({ jobs, onEnqueueJob }) => {
     if (j.status === 'running') q.running++;
     if (j.status === 'failed') q.failed++;
   });
-  const queues = Array.from(queueMap.entries()).map(([name, stats]) => ({ name, ...stats }));
+  // Defined queues show even before they have jobs.
+  queueConfigs.forEach(c => {
+    if (!queueMap.has(c.name)) {
+      queueMap.set(c.name, { pending: 0, running: 0, failed: 0, dlq: 0, lastEnqueued: '-' });
+    }
+  });
+  const configByName = new Map(queueConfigs.map(c => [c.name, c]));
+  const queues = Array.from(queueMap.entries()).map(([name, stats]) => ({ name, ...stats, config: configByName.get(name) }));
   if (queues.length === 0) {
-    queues.push({ name: 'default', pending: 0, running: 0, failed: 0, dlq: 0, lastEnqueued: '-' });
+    queues.push({ name: 'default', pending: 0, running: 0, failed: 0, dlq: 0, lastEnqueued: '-', config: undefined });
   }
 
   const submitJob = async (e: React.FormEvent) => {
@@ -55,11 +62,11 @@ export const Queues: React.FC<QueuesProps> = ({ jobs, onEnqueueJob }) => {
       queue: formData.queue,
       payload: parsedPayload,
       status: 'pending',
-      max_retries: Number(formData.max_retries),
-      timeout: Number(formData.timeout) * 1000000, 
-      retry_policy: { initial_interval: 1000000000, max_interval: 10000000000, max_attempts: Number(formData.max_retries) }
     };
 
+    // Left blank, the timeout falls back to the queue's default.
+    if (formData.timeout !== '') payloadObj.timeout = Number(formData.timeout) * 1000000;
+
     if (formData.cron_expr) payloadObj.cron_expr = formData.cron_expr;
     if (formData.dependencies) payloadObj.dependencies = formData.dependencies.split(',').map(s => s.trim());
 
@@ -109,6 +116,14 @@ export const Queues: React.FC<QueuesProps> = ({ jobs, onEnqueueJob }) => {
                 <p className="text-2xl font-semibold text-primary">{q.dlq.toLocaleString()}</p>
               </div>
             </div>
+            {q.config && (
+              <div className="mt-4 pt-4 border-t border-border grid grid-cols-2 gap-2 text-[11px] text-secondary font-mono">
+                <span>Priority / {q.config.priority || 0}</span>
+                <span>Retries / {q.config.max_retries || 0}</span>
+                <span>Timeout / {q.config.timeout ? `${q.config.timeout / 1e9}s` : 'none'}</span>
+                <span>Backoff / {q.config.retry_policy?.strategy || 'exponential'}</span>
+              </div>
+            )}
             <div className="mt-4 pt-4 border-t border-border text-[11px] text-secondary font-mono">
               Last Enqueued / {q.lastEnqueued !== '-' ? new Date(q.lastEnqueued).toLocaleTimeString() : 'Never'}
             </div>
@@ -155,7 +170,7 @@ export const Queues: React.FC<QueuesProps> = ({ jobs, onEnqueueJob }) => {
                   </div>
                   <div>
                     <label className="block text-xs font-semibold text-secondary uppercase tracking-wider mb-2">Timeout (MS)</label>
-                    <input type="number" min="1000" value={formData.timeout} onChange={e => setFormData({...formData, timeout: Number(e.target.value)})} className="w-full px-3 py-2 bg-black/20 border border-border rounded text-sm text-primary focus:ring-1 focus:ring-blue-500 focus:outline-none placeholder:text-secondary/50" required />
+                    <input type="number" min="1000" placeholder="Queue default" value={formData.timeout} onChange={e => setFormData({...formData, timeout: e.target.value})} className="w-full px-3 py-2 bg-black/20 border border-border rounded text-sm text-primary focus:ring-1 focus:ring-blue-500 focus:outline-none placeholder:text-secondary/50" />
                   </div>
                 </div>
               </form>
@@ -1,5 +1,5 @@
 import React from 'react';
-import type { WorkerStats } from '../types';
+import type { WorkerStats, ScalingStatus } from '../types';
 import { Badge } from '../components/ui/Badge';
 import { Card } from '../components/ui/Card';
 import { Table, TableHeader, TableRow, TableHead, TableCell } from '../components/ui/Table';
@@ -7,9 +7,10 @@ import { Activity } from 'lucide-react';
 
 interface WorkersProps {
   workers: WorkerStats[];
+  scaling: ScalingStatus | null;
 }
 
-export const Workers: React.FC<WorkersProps> = ({ workers }) => {
+export const Workers: React.FC<WorkersProps> = ({ workers, scaling }) => {
   return (
     <div className="space-y-6 animate-in fade-in slide-in-from-bottom-2 duration-300">
       <div className="flex items-center justify-between mb-6">
@@ -28,6 +29,8 @@ export const Workers: React.FC<WorkersProps> = ({ workers }) => {
               <TableHead>Worker ID</TableHead>
               <TableHead>Status</TableHead>
               <TableHead>Current Execution</TableHead>
+              <TableHead>Slots</TableHead>
+              <TableHead>Labels</TableHead>
               <TableHead>Jobs Processed</TableHead>
               <TableHead>Last Heartbeat</TableHead>
             </tr>
@@ -35,20 +38,20 @@ export const Workers: React.FC<WorkersProps> = ({ workers }) => {
           <tbody>
             {workers.length === 0 ? (
               <tr>
-                <td colSpan={5} className="px-4 py-8 text-center text-sm text-secondary">
+                <td colSpan={7} className="px-4 py-8 text-center text-sm text-secondary">
                   No active workers found in network registry.
                 </td>
               </tr>
             ) : (
               workers.map((w) => {
                 const isAlive = (new Date().getTime() - new Date(w.last_heartbeat).getTime()) < 60000;
-                const statusState = !isAlive ? 'dead' : w.status === 'running' ? 'running' : 'pending'; 
+                const statusState = w.status === 'stopped' ? 'stopped' : !isAlive ? 'dead' : w.status === 'running' ? 'running' : 'pending'; 
                 
                 return (
                   <TableRow key={w.id}>
                     <TableCell className="font-mono text-sm font-medium text-primary">{w.id}</TableCell>
                     <TableCell>
-                      <Badge variant={statusState}>{statusState === 'pending' ? 'idle' : statusState === 'dead' ? 'offline' : 'busy'}</Badge>
+                      <Badge variant={statusState}>{statusState === 'pending' ? 'idle' : statusState === 'dead' ? 'offline' : statusState === 'stopped' ? 'stopped' : 'busy'}</Badge>
                     </TableCell>
                     <TableCell className="font-mono text-xs text-secondary">
                       {w.current_job_id ? (
@@ -57,11 +60,26 @@ export const Workers: React.FC<WorkersProps> = ({ workers }) => {
                         <span className="opacity-50">---</span>
                       )}
                     </TableCell>
+                    <TableCell className="font-mono text-xs text-secondary">
+                      <span className="text-primary">{w.used_slots || 0}</span> / {w.capacity || 1}
+                      <span className="opacity-50 ml-2">({w.active_jobs || 0} jobs)</span>
+                    </TableCell>
+                    <TableCell className="font-mono text-xs text-secondary">
+                      {w.labels && Object.keys(w.labels).length > 0 ? (
+                        <div className="flex flex-wrap gap-1">
+                          {Object.entries(w.labels).map(([k, v]) => (
+                            <span key={k} className="bg-white/5 px-1.5 py-0.5 rounded border border-border">{k}={v}</span>
+                          ))}
+                        </div>
+                      ) : (
+                        <span className="opacity-50">---</span>
+                      )}
+                    </TableCell>
                     <TableCell className="text-primary font-medium">
                       {(w.jobs_completed || 0).toLocaleString()}
                     </TableCell>
                     <TableCell className="text-xs text-secondary flex items-center gap-2 mt-2">
-                       <span className={`w-1.5 h-1.5 rounded-full ${isAlive ? 'bg-status-success' : 'bg-status-dead'}`}></span>
+                       <span className={`w-1.5 h-1.5 rounded-full ${isAlive && statusState !== 'stopped' ? 'bg-status-success' : 'bg-status-dead'}`}></span>
                        {new Date(w.last_heartbeat).toLocaleTimeString()}
                     </TableCell>
                   </TableRow>
@@ -71,6 +89,30 @@ export const Workers: React.FC<WorkersProps> = ({ workers }) => {
           </tbody>
         </Table>
       </Card>
+
+      {scaling && (
+        <Card className="p-0 overflow-hidden">
+          <div className="px-6 py-5 border-b border-border flex justify-between items-center bg-black/20">
+            <h3 className="text-sm font-semibold text-primary uppercase tracking-wide">Autoscaling</h3>
+            <span className="text-xs text-secondary font-mono">
+              {scaling.workers} workers ({scaling.min}&ndash;{scaling.max}) &middot; {(scaling.latency / 1e6).toFixed(0)} ms avg latency
+            </span>
+          </div>
+          {scaling.decisions.length === 0 ? (
+            <p className="px-6 py-6 text-sm text-secondary">No scaling decisions yet.</p>
+          ) : (
+            <ul className="divide-y divide-border">
+              {[...scaling.decisions].reverse().map((d, idx) => (
+                <li key={idx} className="px-6 py-3 flex items-center gap-4 text-xs">
+                  <span className="text-secondary w-24">{new Date(d.time).toLocaleTimeString()}</span>
+                  <span className="font-mono text-primary w-16">{d.from} &rarr; {d.to}</span>
+                  <span className="text-secondary">{d.reason}</span>
+                </li>
+              ))}
+            </ul>
+          )}
+        </Card>
+      )}
     </div>
   );
 };
@@ -3,12 +3,18 @@ export interface Attempt {
   finished_at?: string;
   status: string;
   error?: string;
+  exit_code?: number;
+  stack?: string;
 }
 
 export interface RetryPolicy {
   initial_interval: number;
   max_interval: number;
   max_attempts: number;
+  strategy?: string;
+  multiplier?: number;
+  jitter?: string;
+  schedule?: number[];
 }
 
 export interface Job {
@@ -16,24 +22,86 @@ export interface Job {
   queue: string;
   payload: Record<string, unknown>;
   status: string;
+  type?: string;
+  executor?: string;
+  executor_config?: Record<string, unknown>;
   max_retries: number;
   attempts: Attempt[];
   dependencies?: string[];
   dependents?: string[];
+  parent_id?: string;
   run_at?: string;
   cron_expr?: string;
   retry_policy: RetryPolicy;
   timeout: number;
+  priority?: number;
+  weight?: number;
+  progress?: JobProgress;
+  required_labels?: Record<string, string>;
+  preferred_labels?: Record<string, string>;
+  unplaced_reason?: string;
+  cancel_requested?: boolean;
+  result?: unknown;
+  result_expires_at?: string;
+}
+
+export interface QueueConfig {
+  name: string;
+  retry_policy: RetryPolicy;
+  max_retries?: number;
+  timeout?: number;
+  priority?: number;
+  executor?: string;
+  retention?: number;
 }
 
 export interface WorkerStats {
   id: string;
   status: string;
   current_job_id?: string;
+  labels?: Record<string, string>;
+  capacity: number;
+  active_jobs: number;
+  used_slots: number;
   jobs_completed: number;
   last_heartbeat: string;
 }
 
+export interface JobTree extends Job {
+  children?: JobTree[];
+}
+
+export interface JobLog {
+  id: number;
+  job_id: string;
+  attempt: number;
+  level: string;
+  message: string;
+  fields?: Record<string, unknown>;
+  time: string;
+}
+
+export interface JobProgress {
+  percent: number;
+  message?: string;
+  updated_at: string;
+}
+
+export interface ScalingDecision {
+  time: string;
+  from: number;
+  to: number;
+  reason: string;
+}
+
+export interface ScalingStatus {
+  min: number;
+  max: number;
+  workers: number;
+  latency: number; // ns
+  decisions: ScalingDecision[];
+}
+
 export interface DLQJob {
   id: string;
   queue: string;
@@ -0,0 +1,44 @@
+// Package worker runs Wida jobs inside your own program, with executors of
+// your own such as a handler.Registry. Its workers claim jobs from the same
+// database as widad's and can run alongside them; the schema is created by
+// widad.
+//
+//	reg := handler.NewRegistry()
+//	handler.Handle(reg, "send_welcome", sendWelcome)
+//
+//	pool := worker.NewPool("mailer-1", db, []string{"emails"})
+//	pool.RegisterExecutor(worker.DefaultExecutor, reg)
+//	pool.Start(ctx, 2, 5)
+//	defer pool.Stop(30 * time.Second)
+package worker
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/theb0imanuu/wida/internal/core"
+	"github.com/theb0imanuu/wida/internal/store/postgres"
+	"github.com/theb0imanuu/wida/internal/worker"
+)
+
+// Executor runs jobs; Middleware wraps an Executor. They are re-exported so
+// that programs outside this module can write their own.
+type (
+	Job          = core.Job
+	Executor     = core.Executor
+	ExecutorFunc = core.ExecutorFunc
+	Middleware   = core.Middleware
+)
+
+// DefaultExecutor is the executor jobs that name none are run by.
+const DefaultExecutor = core.DefaultExecutor
+
+// Pool is a set of workers claiming jobs from queues. Register executors
+// before calling Start, and Stop it to drain running jobs on shutdown.
+type Pool struct {
+	*worker.Pool
+}
+
+// NewPool returns a pool named id that works queues in the database behind
+// db.
+func NewPool(id string, db *pgxpool.Pool, queues []string) *Pool {
+	return &Pool{worker.NewPool(id, postgres.NewStore(db), queues)}
+}